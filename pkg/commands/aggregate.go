@@ -0,0 +1,111 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+)
+
+const AggregateExamples string = `   v3ctl aggregate datalake sales -x "sum(price),avg(qty),count(*)" -b region
+   v3ctl aggregate datalake sales -x "min(ts),max(ts)" -q "qty>10" -o csv`
+
+type aggregateCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	aggregates     []string
+	groupBy        []string
+	filter         string
+	output         string
+}
+
+func NewCmdAggregate(rootCommandeer *RootCommandeer) *aggregateCommandeer {
+
+	commandeer := &aggregateCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "aggregate [container-name] [table-path] [-x aggregates] [-b attrs] [-q query]",
+		Short:   "Compute aggregates (count, sum, avg, min, max) over records, optionally grouped by attributes",
+		Example: AggregateExamples,
+		Aliases: []string{"agg"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.aggregate()
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&commandeer.aggregates, "aggregates", "x", []string{"count(*)"},
+		"Aggregates to compute seperated by ',', e.g. sum(price),avg(qty),count(*)")
+	cmd.Flags().StringSliceVarP(&commandeer.groupBy, "group-by", "b", []string{}, "Attributes to group by seperated by ','")
+	cmd.Flags().StringVarP(&commandeer.filter, "filter", "q", "", "GetItems query filter string, see getitems help for more")
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", "table", "Output format [table | csv | json]")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (c *aggregateCommandeer) aggregate() error {
+
+	aggregates, err := utils.ParseAggregates(c.aggregates)
+	if err != nil {
+		return err
+	}
+	if len(aggregates) == 0 {
+		return fmt.Errorf("missing aggregates (-x)")
+	}
+	if err := validateFormat(c.output, "table", "csv", "json"); err != nil {
+		return err
+	}
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	aggregator := utils.NewAggregator(c.groupBy, aggregates)
+	input := v3io.GetItemsInput{
+		Path: endWithSlash(root.dirPath), Filter: c.filter, AttributeNames: aggregator.Attributes()}
+	root.logger.DebugWith("GetItems for aggregate", "input", input)
+	iter, err := utils.NewAsyncItemsCursor(container, &input, root.v3iocfg.QryWorkers, []string{}, root.logger, 0)
+	if err != nil {
+		return err
+	}
+
+	for iter.Next() {
+		aggregator.Add(iter.GetFields())
+	}
+
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+
+	return writeRows(root.out, c.output, aggregator.Columns(), aggregator.Rows())
+}
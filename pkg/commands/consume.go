@@ -0,0 +1,237 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const ConsumeExamples string = `   v3ctl consume datalake mystream --group billing -w
+   v3ctl consume datalake mystream --group billing --reset time --time -1h
+   v3ctl consume datalake mystream --group billing --reset latest`
+
+// consumerFlags are the options of commands reading a stream as a consumer group
+type consumerFlags struct {
+	group           string
+	checkpointTable string
+	start           string
+	reset           string
+	resetTime       string
+	maxrec          int
+	watch           int
+	unordered       bool
+	commitInterval  time.Duration
+	commitBatch     int
+	lease           time.Duration
+}
+
+func addConsumerFlags(cmd *cobra.Command, flags *consumerFlags, defaultGroup string) {
+	cmd.Flags().StringVar(&flags.group, "group", defaultGroup, "Consumer group name")
+	if defaultGroup == "" {
+		cmd.MarkFlagRequired("group")
+	}
+	cmd.Flags().StringVar(&flags.checkpointTable, "checkpoint-table", "",
+		"Table for the consumer group checkpoints (default <stream-path>_groups)")
+	cmd.Flags().StringVar(&flags.start, "start", "earliest",
+		"Where to start reading shards without a checkpoint [earliest | latest]")
+	cmd.Flags().StringVar(&flags.reset, "reset", "",
+		"Ignore the checkpoints and start from [earliest | latest | time]")
+	cmd.Flags().StringVar(&flags.resetTime, "time", "",
+		"Starting time for --reset time, RFC3339, epoch seconds or relative (e.g. -1h)")
+	cmd.Flags().IntVarP(&flags.maxrec, "max-rec", "m", 50, "Max Records/Items to get per call")
+	cmd.Flags().IntVarP(&flags.watch, "watch", "w", 0, "Watch the stream, read every N secounds (blocking)")
+	cmd.Flags().Lookup("watch").NoOptDefVal = "2"
+	cmd.Flags().BoolVar(&flags.unordered, "unordered", false,
+		"Read records as they arrive from the shards rather than merged by arrival time")
+	cmd.Flags().DurationVar(&flags.commitInterval, "commit-interval", 5*time.Second,
+		"Commit the checkpoints (and renew the group lease) at this interval")
+	cmd.Flags().IntVar(&flags.commitBatch, "commit-batch", 0,
+		"Also commit the checkpoints after every N records (0 to commit only by interval)")
+	cmd.Flags().DurationVar(&flags.lease, "lease", 30*time.Second,
+		"Group ownership lease, another consumer may take over the group once it expires")
+}
+
+// build the consumer configuration of the stream path from the flags
+func (flags *consumerFlags) config(streamPath string) (*utils.ConsumerConfig, error) {
+	if flags.commitInterval <= 0 || flags.commitInterval >= flags.lease {
+		return nil, fmt.Errorf("--commit-interval must be positive and shorter than --lease")
+	}
+
+	config := utils.ConsumerConfig{
+		Lease:          flags.lease,
+		CommitInterval: flags.commitInterval,
+		CommitBatch:    flags.commitBatch,
+		Start:          v3io.SeekShardInput{Type: v3io.SeekShardInputTypeEarliest},
+		Reader: utils.StreamReaderConfig{
+			Limit: flags.maxrec, Watch: time.Duration(flags.watch) * time.Second, Unordered: flags.unordered},
+	}
+
+	switch strings.ToLower(flags.reset) {
+	case "":
+	case "earliest":
+		config.Reset = &v3io.SeekShardInput{Type: v3io.SeekShardInputTypeEarliest}
+	case "latest":
+		config.Reset = &v3io.SeekShardInput{Type: v3io.SeekShardInputTypeLatest}
+	case "time":
+		if flags.resetTime == "" {
+			return nil, fmt.Errorf("missing --time for --reset time")
+		}
+		t, err := utils.ParseTime(flags.resetTime, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		config.Reset = &v3io.SeekShardInput{Type: v3io.SeekShardInputTypeTime, Timestamp: int(t.Unix())}
+	default:
+		return nil, fmt.Errorf("Reset type %s is invalid, use earliest | latest | time", flags.reset)
+	}
+
+	switch strings.ToLower(flags.start) {
+	case "earliest":
+	case "latest":
+		config.Start.Type = v3io.SeekShardInputTypeLatest
+	default:
+		return nil, fmt.Errorf("Start position %s is invalid, use earliest | latest", flags.start)
+	}
+
+	table := flags.checkpointTable
+	if table == "" {
+		table = strings.TrimSuffix(streamPath, "/") + "_groups"
+	}
+	config.CheckpointPath = endWithSlash(table) + flags.group
+
+	hostname, _ := os.Hostname()
+	config.Owner = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	return &config, nil
+}
+
+// stop the consumer on interrupt, so the records processed so far are committed
+func stopOnInterrupt(consumer *utils.Consumer) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			consumer.Stop()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+type consumeCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	consumer       consumerFlags
+	output         recordOutput
+}
+
+func NewCmdConsume(rootCommandeer *RootCommandeer) *consumeCommandeer {
+
+	commandeer := &consumeCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "consume [container-name] [stream-path] --group name [--reset earliest|latest|time]",
+		Short:   "Read stream records as a consumer group, resuming from the group checkpoints",
+		Example: ConsumeExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.consume()
+		},
+	}
+
+	addConsumerFlags(cmd, &commandeer.consumer, "")
+	addRecordOutputFlags(cmd, &commandeer.output)
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func (c *consumeCommandeer) consume() error {
+
+	root := c.rootCommandeer
+	if root.dirPath == "" {
+		return fmt.Errorf("missing stream path")
+	}
+
+	config, err := c.consumer.config(root.dirPath)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newRecordWriter(root.out, c.output)
+	if err != nil {
+		return err
+	}
+
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	shards, err := utils.ListShards(container, endWithSlash(root.dirPath))
+	if err != nil {
+		return err
+	}
+
+	consumer, err := utils.NewConsumer(container, shards, *config)
+	if err != nil {
+		return err
+	}
+	defer stopOnInterrupt(consumer)()
+	defer writer.close()
+
+	for consumer.Next() {
+		record := consumer.Record()
+		if err := writer.write(record); err != nil {
+			consumer.Close()
+			return err
+		}
+		if err := consumer.Done(record); err != nil {
+			consumer.Close()
+			return err
+		}
+	}
+
+	if err := consumer.Close(); err != nil {
+		return err
+	}
+	return consumer.Err()
+}
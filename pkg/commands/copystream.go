@@ -0,0 +1,221 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"path"
+	"strings"
+	"time"
+)
+
+const CopyStreamExamples string = `   v3ctl copystream datalake/events datalake/events_v2
+   v3ctl copystream datalake/events backup/events --from -1d -b 500
+   v3ctl copystream datalake/events test/events --from 2018-06-01T22:00:00Z --to 2018-06-01T23:00:00Z --replay --speed 10`
+
+type copyStreamCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	seek           string
+	from           string
+	to             string
+	maxrec         int
+	batchSize      int
+	retries        int
+	retryDelay     time.Duration
+	replay         bool
+	speed          float64
+}
+
+func NewCmdCopyStream(rootCommandeer *RootCommandeer) *copyStreamCommandeer {
+
+	commandeer := &copyStreamCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "copystream [container/source-stream] [container/target-stream]",
+		Short:   "Copy the records of a stream to another stream, keeping partition keys and client info",
+		Example: CopyStreamExamples,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.copy(args[0], args[1])
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.seek, "seek", "k", "EARLIEST", "Relative source stream location [EARLIEST | LATEST]")
+	cmd.Flags().StringVar(&commandeer.from, "from", "",
+		"Copy records from this time, RFC3339, epoch or relative to now (e.g. -15m, -2h, -1d)")
+	cmd.Flags().StringVar(&commandeer.to, "to", "", "Copy records up to this time, RFC3339, epoch or relative to now")
+	cmd.Flags().IntVarP(&commandeer.maxrec, "max-rec", "m", 100, "Max Records to get per call")
+	cmd.Flags().IntVarP(&commandeer.batchSize, "batch-size", "b", 100, "Number of records per PutRecords call")
+	cmd.Flags().IntVar(&commandeer.retries, "retries", 3,
+		"Number of times to retry failed records, the later records of a failed record's partition key are resent\nwith it to keep their order (and may be written twice)")
+	cmd.Flags().DurationVar(&commandeer.retryDelay, "retry-delay", 200*time.Millisecond,
+		"Delay before the first retry, doubled on every retry")
+	cmd.Flags().BoolVar(&commandeer.replay, "replay", false, "Reproduce the original time between records")
+	cmd.Flags().Float64Var(&commandeer.speed, "speed", 1, "Replay speed factor, e.g. 10 replays an hour in 6 minutes")
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func (c *copyStreamCommandeer) copy(source, target string) error {
+
+	if c.batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if c.speed <= 0 {
+		return fmt.Errorf("replay speed must be positive")
+	}
+
+	input := v3io.SeekShardInput{}
+	switch strings.ToLower(c.seek) {
+	case "earliest":
+		input.Type = v3io.SeekShardInputTypeEarliest
+	case "latest", "late":
+		input.Type = v3io.SeekShardInputTypeLatest
+	default:
+		return fmt.Errorf("Stream seek type %s is invalid, use earliest | latest (or --from)", c.seek)
+	}
+
+	now := time.Now()
+	config := utils.StreamReaderConfig{Limit: c.maxrec}
+	var err error
+	if c.from != "" {
+		if config.From, err = utils.ParseTime(c.from, now); err != nil {
+			return err
+		}
+		input.Type = v3io.SeekShardInputTypeTime
+		input.Timestamp = int(config.From.Unix())
+	}
+	if c.to != "" {
+		if config.To, err = utils.ParseTime(c.to, now); err != nil {
+			return err
+		}
+	}
+
+	sourceContainerName, sourcePath, err := splitContainerPath(source)
+	if err != nil {
+		return err
+	}
+	targetContainerName, targetPath, err := splitContainerPath(target)
+	if err != nil {
+		return err
+	}
+
+	if sourceContainerName == targetContainerName &&
+		path.Clean("/"+sourcePath) == path.Clean("/"+targetPath) {
+		return fmt.Errorf("the source and target are the same stream")
+	}
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+	sourceContainer, err := root.openContainer(sourceContainerName)
+	if err != nil {
+		return err
+	}
+	targetContainer, err := root.openContainer(targetContainerName)
+	if err != nil {
+		return err
+	}
+
+	shards, err := utils.ListShards(sourceContainer, endWithSlash(sourcePath))
+	if err != nil {
+		return err
+	}
+	if _, err := utils.ListShards(targetContainer, endWithSlash(targetPath)); err != nil {
+		return fmt.Errorf("target stream: %v", err)
+	}
+
+	// records are read merged by arrival time, so records of a partition key keep their order
+	reader := utils.NewStreamReader(sourceContainer, shards,
+		func(shard utils.StreamShard) v3io.SeekShardInput { return input }, config)
+	defer reader.Close()
+
+	copied, failed := 0, 0
+	batch := []*v3io.StreamRecord{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := utils.PutRecordsWithRetry(targetContainer, endWithSlash(targetPath), batch, c.retries, c.retryDelay, true)
+		if err != nil {
+			return fmt.Errorf("Error in PutRecords operation (%v)", err)
+		}
+		for _, result := range results {
+			if result.ErrorCode != 0 {
+				failed++
+				root.logger.WarnWith("Failed to copy record", "code", result.ErrorCode, "message", result.ErrorMessage)
+			} else {
+				copied++
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	var replayStart, firstArrival time.Time
+	for reader.Next() {
+		record := reader.Record()
+
+		if c.replay {
+			arrival := utils.RecordTime(&record.GetRecordsResult)
+			if replayStart.IsZero() {
+				replayStart, firstArrival = time.Now(), arrival
+			}
+			due := replayStart.Add(time.Duration(float64(arrival.Sub(firstArrival)) / c.speed))
+			if time.Until(due) > 0 {
+				if err := flush(); err != nil {
+					return err
+				}
+				time.Sleep(time.Until(due))
+			}
+		}
+
+		streamRecord := v3io.StreamRecord{Data: record.Data, ClientInfo: record.ClientInfo, PartitionKey: record.PartitionKey}
+		batch = append(batch, &streamRecord)
+		if len(batch) >= c.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := reader.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(root.out, "Copied %d records, %d failed\n", copied, failed)
+	if failed > 0 {
+		return fmt.Errorf("Failed to copy %d records", failed)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"sort"
+)
+
+type countCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	filter         string
+	groupBy        string
+	perSegment     bool
+}
+
+func NewCmdCount(rootCommandeer *RootCommandeer) *countCommandeer {
+
+	commandeer := &countCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "count [container-name] [table-path] [-q query] [-b attr]",
+		Short:   "Count records matching an optional filter",
+		Aliases: []string{"cnt"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.count()
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.filter, "filter", "q", "", "GetItems query filter string, see getitems help for more")
+	cmd.Flags().StringVarP(&commandeer.groupBy, "group-by", "b", "", "Count records per distinct value of this attribute")
+	cmd.Flags().BoolVarP(&commandeer.perSegment, "per-segment", "S", false, "Show the count of every scan segment")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (c *countCommandeer) count() error {
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	attrs := []string{"__name"}
+	if c.groupBy != "" {
+		attrs = append(attrs, c.groupBy)
+	}
+
+	input := v3io.GetItemsInput{Path: endWithSlash(root.dirPath), Filter: c.filter, AttributeNames: attrs}
+	root.logger.DebugWith("GetItems for count", "input", input)
+	iter, err := utils.NewAsyncItemsCursor(container, &input, root.v3iocfg.QryWorkers, []string{}, root.logger, 0)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	segments := make([]int, iter.TotalSegments())
+	groups := map[string]int{}
+
+	for iter.Next() {
+		total++
+		if segment := iter.GetSegment(); segment < len(segments) {
+			segments[segment]++
+		}
+		if c.groupBy != "" {
+			groups[groupKey(iter.GetField(c.groupBy))]++
+		}
+	}
+
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+
+	out := root.out
+	if c.perSegment {
+		for segment, cnt := range segments {
+			fmt.Fprintf(out, "Segment %d: %d\n", segment, cnt)
+		}
+	}
+
+	if c.groupBy != "" {
+		keys := make([]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(out, "%-30s  %s\n", c.groupBy, "COUNT")
+		for _, key := range keys {
+			fmt.Fprintf(out, "%-30s  %d\n", key, groups[key])
+		}
+	}
+
+	fmt.Fprintf(out, "Total: %d\n", total)
+	return nil
+}
+
+// convert an attribute value to a group name, missing attributes are grouped under <null>
+func groupKey(val interface{}) string {
+	if val == nil {
+		return "<null>"
+	}
+	if bytes, ok := val.([]byte); ok {
+		return string(bytes)
+	}
+	return fmt.Sprint(val)
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nuclio/logger"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"hash/fnv"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const DiffTableExamples string = `   v3ctl difftable datalake/mytable backup/mytable
+   v3ctl difftable datalake/mytable datalake/mytable_v2 -a name,age -q "age>30"`
+
+type diffTableCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	attributes     []string
+	filter         string
+}
+
+// a single NDJSON line of the difftable output
+type tableDiff struct {
+	Key    string               `json:"key"`
+	Status string               `json:"status"`
+	Diffs  map[string]valueDiff `json:"diffs,omitempty"`
+}
+
+type valueDiff struct {
+	Left  interface{} `json:"left"`
+	Right interface{} `json:"right"`
+}
+
+func NewCmdDiffTable(rootCommandeer *RootCommandeer) *diffTableCommandeer {
+
+	commandeer := &diffTableCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "difftable [container1/table1] [container2/table2] [-a attrs] [-q query]",
+		Short: "Compare the records of two tables by key",
+		Long: `Compare the records of two tables by key, writing a json line for every key missing on either side or
+with different attributes. Both tables are scanned in parallel keeping the key and a hash of every record,
+so memory grows with the number of keys (not the record sizes), differing records are read again to report
+their values.`,
+		Example: DiffTableExamples,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.diff(args[0], args[1])
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&commandeer.attributes, "attrs", "a", []string{"*"}, "Columns to compare seperated by ','")
+	cmd.Flags().StringVarP(&commandeer.filter, "filter", "q", "", "GetItems query filter string applied to both tables, see getitems help for more")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (c *diffTableCommandeer) diff(leftPath, rightPath string) error {
+
+	leftContainerName, leftTable, err := splitContainerPath(leftPath)
+	if err != nil {
+		return err
+	}
+	rightContainerName, rightTable, err := splitContainerPath(rightPath)
+	if err != nil {
+		return err
+	}
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+	leftContainer, err := root.openContainer(leftContainerName)
+	if err != nil {
+		return err
+	}
+	rightContainer, err := root.openContainer(rightContainerName)
+	if err != nil {
+		return err
+	}
+
+	type scanResult struct {
+		hashes map[string]uint64
+		err    error
+	}
+
+	// scan both tables in parallel, keeping only a hash of every record so memory is bounded by the number of keys
+	results := make([]chan scanResult, 2)
+	for i, target := range []struct {
+		container *v3io.Container
+		table     string
+	}{{leftContainer, leftTable}, {rightContainer, rightTable}} {
+		results[i] = make(chan scanResult, 1)
+		go func(ch chan scanResult, container *v3io.Container, tablePath string) {
+			hashes, err := c.scan(container, tablePath, root.logger, root.v3iocfg.QryWorkers)
+			ch <- scanResult{hashes: hashes, err: err}
+		}(results[i], target.container, target.table)
+	}
+
+	left, right := <-results[0], <-results[1]
+	if left.err != nil {
+		return fmt.Errorf("Failed to read %s - %v", leftPath, left.err)
+	}
+	if right.err != nil {
+		return fmt.Errorf("Failed to read %s - %v", rightPath, right.err)
+	}
+
+	keys := make([]string, 0, len(left.hashes)+len(right.hashes))
+	for key := range left.hashes {
+		keys = append(keys, key)
+	}
+	for key := range right.hashes {
+		if _, ok := left.hashes[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	missingLeft, missingRight, different := 0, 0, 0
+	encoder := json.NewEncoder(c.rootCommandeer.out)
+	for _, key := range keys {
+		leftHash, inLeft := left.hashes[key]
+		rightHash, inRight := right.hashes[key]
+
+		diff := tableDiff{Key: key}
+		switch {
+		case !inLeft:
+			diff.Status = "missing_left"
+			missingLeft++
+		case !inRight:
+			diff.Status = "missing_right"
+			missingRight++
+		case leftHash == rightHash:
+			continue
+		default:
+			// read the differing records again to report the attribute values
+			leftItem, err := c.getItem(leftContainer, leftTable, key)
+			if err != nil {
+				return fmt.Errorf("Failed to read %s from %s - %v", key, leftPath, err)
+			}
+			rightItem, err := c.getItem(rightContainer, rightTable, key)
+			if err != nil {
+				return fmt.Errorf("Failed to read %s from %s - %v", key, rightPath, err)
+			}
+			diff.Diffs = compareItems(leftItem, rightItem)
+			if len(diff.Diffs) == 0 {
+				continue
+			}
+			diff.Status = "different"
+			different++
+		}
+
+		if err := encoder.Encode(diff); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Compared %d keys: %d missing in %s, %d missing in %s, %d different\n",
+		len(keys), missingLeft, leftPath, missingRight, rightPath, different)
+	if missingLeft+missingRight+different > 0 {
+		return fmt.Errorf("tables %s and %s differ", leftPath, rightPath)
+	}
+
+	return nil
+}
+
+// read the hash of the compared attributes of every table record by key
+func (c *diffTableCommandeer) scan(container *v3io.Container, tablePath string, logger logger.Logger,
+	workers int) (map[string]uint64, error) {
+
+	attrs := c.attributes
+	if !containsString(attrs, "__name") {
+		attrs = append([]string{"__name"}, attrs...)
+	}
+
+	input := v3io.GetItemsInput{Path: endWithSlash(tablePath), Filter: c.filter, AttributeNames: attrs}
+	logger.DebugWith("GetItems for difftable", "input", input)
+	iter, err := utils.NewAsyncItemsCursor(container, &input, workers, []string{}, logger, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := map[string]uint64{}
+	for iter.Next() {
+		item := iter.GetFields()
+		key := fmt.Sprint(item["__name"])
+		delete(item, "__name")
+		hashes[key] = hashItem(item)
+	}
+
+	return hashes, iter.Err()
+}
+
+func (c *diffTableCommandeer) getItem(container *v3io.Container, tablePath, key string) (map[string]interface{}, error) {
+	input := v3io.GetItemInput{Path: endWithSlash(tablePath) + url.QueryEscape(key), AttributeNames: c.attributes}
+	resp, err := container.Sync.GetItem(&input)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Release()
+
+	item := resp.Output.(*v3io.GetItemOutput).Item
+	delete(item, "__name")
+	return item, nil
+}
+
+// hash the attribute names, types and values of an item, independent of the attribute order
+func hashItem(item map[string]interface{}) uint64 {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := fnv.New64a()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s\x00%T\x00%v\x00", name, item[name], item[name])
+	}
+	return hash.Sum64()
+}
+
+// return the attributes with different values, attributes missing on one side have a nil value
+func compareItems(left, right map[string]interface{}) map[string]valueDiff {
+	diffs := map[string]valueDiff{}
+	for name, val := range left {
+		if !reflect.DeepEqual(val, right[name]) {
+			diffs[name] = valueDiff{Left: val, Right: right[name]}
+		}
+	}
+	for name, val := range right {
+		if _, ok := left[name]; !ok {
+			diffs[name] = valueDiff{Left: nil, Right: val}
+		}
+	}
+
+	return diffs
+}
+
+// split a container/path argument
+func splitContainerPath(arg string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(arg, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || strings.Trim(parts[1], "/") == "" {
+		return "", "", fmt.Errorf("invalid path '%s', expected container/path", arg)
+	}
+
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type editItemCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	noRetry        bool
+	validate       bool
+	strict         bool
+}
+
+func NewCmdEditItem(rootCommandeer *RootCommandeer) *editItemCommandeer {
+
+	commandeer := &editItemCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "edititem [container-name] [table-path/key]",
+		Short: "Edit record fields in $EDITOR and update them if the record was not changed meanwhile",
+		Long: `Edit record fields in $EDITOR (typed json, see getitem --typed) and update the changed and removed
+fields, the update is conditioned on the record modification time so concurrent changes are not overwritten.`,
+		Aliases: []string{"ei"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.edit()
+		},
+	}
+
+	cmd.Flags().BoolVar(&commandeer.noRetry, "no-retry", false,
+		"Abort when the record was changed while editing, rather than asking to edit the latest version")
+	addValidateFlags(cmd, &commandeer.validate, &commandeer.strict)
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func (c *editItemCommandeer) edit() error {
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	var schema utils.V3ioSchema
+	if c.validate || c.strict {
+		if schema, err = loadItemSchema(container, root.dirPath); err != nil {
+			return err
+		}
+	}
+
+	// the edits of an attempt which failed the condition, re-applied to the latest version of the record
+	var lastOriginal, lastEdited map[string]interface{}
+	for {
+		input := v3io.GetItemInput{Path: root.dirPath, AttributeNames: []string{"*", "__mtime_secs", "__mtime_nsecs"}}
+		resp, err := container.Sync.GetItem(&input)
+		if err != nil {
+			return fmt.Errorf("Error in GetItem operation (%v)", err)
+		}
+		item := resp.Output.(*v3io.GetItemOutput).Item
+		resp.Release()
+
+		// the update is conditioned on the modification time read with the record
+		mtimeSecs, secsOk := item["__mtime_secs"].(int)
+		mtimeNsecs, nsecsOk := item["__mtime_nsecs"].(int)
+		if !secsOk || !nsecsOk {
+			return fmt.Errorf("The record %s has no modification time, it can't be edited safely", root.dirPath)
+		}
+
+		// system attributes (__name, __mtime_secs, ..) are not editable
+		original := map[string]interface{}{}
+		for name, val := range item {
+			if !strings.HasPrefix(name, "__") {
+				original[name] = val
+			}
+		}
+
+		draft := original
+		if lastEdited != nil {
+			draft = applyEdits(original, lastOriginal, lastEdited)
+			fmt.Fprintln(os.Stderr, "Your changes were applied to the latest version of the record, review them in the editor")
+		}
+
+		edited, err := c.editInEditor(draft)
+		if err != nil {
+			return err
+		}
+
+		expression, err := updateExpression(original, edited)
+		if err != nil {
+			return err
+		}
+		if expression == "" {
+			fmt.Fprintln(root.out, "No changes")
+			return nil
+		}
+		if err := utils.CheckUpdateExpression(expression); err != nil {
+			return err
+		}
+
+		if schema != nil {
+			if err := schema.ValidateUpdate(expression, c.strict); err != nil {
+				return err
+			}
+		}
+
+		condition := fmt.Sprintf("__mtime_secs == %d and __mtime_nsecs == %d", mtimeSecs, mtimeNsecs)
+		root.logger.DebugWith("UpdateItem for edititem", "expression", expression, "condition", condition)
+		err = container.Sync.UpdateItem(&v3io.UpdateItemInput{Path: root.dirPath, Expression: &expression, Condition: condition})
+		if err == nil {
+			fmt.Fprintln(root.out, "Record updated")
+			return nil
+		}
+		if !utils.IsConditionFailed(err) {
+			return fmt.Errorf("Error in UpdateItem operation (%v)", err)
+		}
+
+		if c.noRetry {
+			return fmt.Errorf("The record was changed while editing, update aborted. Unsaved changes: %s", expression)
+		}
+		confirmedByUser, err := getConfirmation("The record was changed while editing, apply your changes to the latest version?")
+		if err != nil {
+			return err
+		}
+		if !confirmedByUser {
+			return fmt.Errorf("Update aborted, the record was changed while editing. Unsaved changes: %s", expression)
+		}
+		lastOriginal, lastEdited = original, edited
+	}
+}
+
+// apply the changes made from original to edited on top of the latest attributes
+func applyEdits(latest, original, edited map[string]interface{}) map[string]interface{} {
+	draft := make(map[string]interface{}, len(latest))
+	for name, val := range latest {
+		draft[name] = val
+	}
+	for name, val := range edited {
+		if !sameValue(val, original[name]) {
+			draft[name] = val
+		}
+	}
+	for name := range original {
+		if _, ok := edited[name]; !ok {
+			delete(draft, name)
+		}
+	}
+
+	return draft
+}
+
+// write the item to a temp file, open it in the editor and read back the edited attributes
+func (c *editItemCommandeer) editInEditor(item map[string]interface{}) (map[string]interface{}, error) {
+	typed, err := utils.FormatItem(item, true, "")
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.MarshalIndent(typed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := ioutil.TempFile("", "v3ctl-item-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(append(body, '\n'))
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor may include arguments, e.g. "code --wait"
+	args := append(strings.Fields(editor), file.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Editor %s failed (%v)", editor, err)
+	}
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	edited, err := utils.DecodeItemJson(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the edited record (%v)", err)
+	}
+	return edited, nil
+}
+
+// attribute names which can be written in an update expression as is
+var attributeNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// build an update expression setting the changed attributes and removing the deleted ones
+func updateExpression(original, edited map[string]interface{}) (string, error) {
+	names := make([]string, 0, len(edited))
+	for name := range edited {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statements := []string{}
+	for _, name := range names {
+		val := edited[name]
+		if sameValue(val, original[name]) {
+			continue
+		}
+		if strings.HasPrefix(name, "__") {
+			return "", fmt.Errorf("system attribute %s can't be changed", name)
+		}
+		if !attributeNamePattern.MatchString(name) {
+			return "", fmt.Errorf("attribute name '%s' can't be used in an update expression, use putitem", name)
+		}
+
+		literal, err := expressionLiteral(name, val)
+		if err != nil {
+			return "", err
+		}
+		statements = append(statements, fmt.Sprintf("SET %s=%s", name, literal))
+	}
+
+	removed := []string{}
+	for name := range original {
+		if _, ok := edited[name]; !ok {
+			if !attributeNamePattern.MatchString(name) {
+				return "", fmt.Errorf("attribute name '%s' can't be used in an update expression, use putitem", name)
+			}
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		statements = append(statements, "REMOVE "+strings.Join(removed, ", "))
+	}
+
+	return strings.Join(statements, "; "), nil
+}
+
+// compare attribute values, an unchanged NaN is equal to itself
+func sameValue(a, b interface{}) bool {
+	if fa, ok := a.(float64); ok {
+		if fb, ok := b.(float64); ok && math.IsNaN(fa) && math.IsNaN(fb) {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func expressionLiteral(name string, val interface{}) (string, error) {
+	switch v := val.(type) {
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		// the expression parser doesn't accept exponents
+		str := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(str, ".") {
+			str += ".0"
+		}
+		return str, nil
+	case string:
+		if strings.Contains(v, "'") {
+			return "", fmt.Errorf("value for %s contains a quote and can't be set in an update expression", name)
+		}
+		return "'" + v + "'", nil
+	}
+
+	return "", fmt.Errorf("value for %s (%T) can't be set in an update expression, use putitem", name, val)
+}
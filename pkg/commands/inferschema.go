@@ -23,9 +23,11 @@ package commands
 import (
 	"fmt"
 	"github.com/spf13/cobra"
-	"github.com/v3io/frames"
 	"github.com/v3io/v3cli/pkg/utils"
 	"github.com/v3io/v3io-go-http"
+	"math/rand"
+	"strings"
+	"time"
 )
 
 type inferSchemaCommandeer struct {
@@ -33,6 +35,9 @@ type inferSchemaCommandeer struct {
 	rootCommandeer *RootCommandeer
 	keyField       string
 	maxrec         int
+	sample         string
+	hashingBuckets int
+	dryRun         bool
 }
 
 func NewCmdInferSchema(rootCommandeer *RootCommandeer) *inferSchemaCommandeer {
@@ -52,7 +57,11 @@ func NewCmdInferSchema(rootCommandeer *RootCommandeer) *inferSchemaCommandeer {
 	}
 
 	cmd.Flags().StringVarP(&commandeer.keyField, "key", "k", "__name", "name of the key column")
-	cmd.Flags().IntVarP(&commandeer.maxrec, "max-rec", "m", 50, "Max Records/Items to get per call")
+	cmd.Flags().IntVarP(&commandeer.maxrec, "max-rec", "m", 50, "Max Records/Items to sample")
+	cmd.Flags().StringVar(&commandeer.sample, "sample", "stratified",
+		"Sampling method [stratified (evenly from all scan segments) | random (reads the entire table) | first]")
+	cmd.Flags().IntVar(&commandeer.hashingBuckets, "hashing-buckets", 0, "Number of hashing buckets in the schema")
+	cmd.Flags().BoolVar(&commandeer.dryRun, "dry-run", false, "Print the schema without writing it")
 
 	commandeer.cmd = cmd
 
@@ -61,6 +70,16 @@ func NewCmdInferSchema(rootCommandeer *RootCommandeer) *inferSchemaCommandeer {
 
 func (c *inferSchemaCommandeer) inferSchema() error {
 
+	sample := strings.ToLower(c.sample)
+	switch sample {
+	case "stratified", "random", "first":
+	default:
+		return fmt.Errorf("Sampling method %s is invalid, use stratified | random | first", c.sample)
+	}
+	if c.maxrec <= 0 {
+		return fmt.Errorf("max-rec must be positive")
+	}
+
 	if err := c.rootCommandeer.initialize(); err != nil {
 		return err
 	}
@@ -79,51 +98,79 @@ func (c *inferSchemaCommandeer) inferSchema() error {
 		return err
 	}
 
-	out := c.rootCommandeer.out
-	rowSet := []map[string]interface{}{}
-	indicies := []string{}
-
-	for rowNum := 0; rowNum < c.maxrec && iter.Next(); rowNum++ {
-		row := iter.GetFields()
-		rowSet = append(rowSet, row)
-		index, ok := row["__name"]
-		if !ok {
-			return fmt.Errorf("key (__name) was not found in row")
-		}
-		indicies = append(indicies, index.(string))
+	var rowSet []map[string]interface{}
+	switch sample {
+	case "first":
+		rowSet = sampleFirst(iter, c.maxrec)
+	case "stratified":
+		rowSet = sampleStratified(iter, c.maxrec)
+	case "random":
+		rowSet = sampleRandom(iter, c.maxrec)
 	}
 
 	if iter.Err() != nil {
 		return iter.Err()
 	}
 
-	labels := map[string]interface{}{}
-	frame, err := frames.NewFrameFromRows(rowSet, indicies, labels)
-	if err != nil {
-		return fmt.Errorf("Failed to create frame - %v", err)
-	}
-
-	nullSchema := utils.NewSchema(c.keyField)
-	newSchema := utils.NewSchema(c.keyField)
-
-	for _, name := range frame.Names() {
-		col, err := frame.Column(name)
-		if err != nil {
-			return err
-		}
-		err = newSchema.AddColumn(name, col, true)
-		if err != nil {
+	inferrer := utils.NewSchemaInferrer(c.keyField)
+	for _, row := range rowSet {
+		if err := inferrer.Add(row); err != nil {
 			return err
 		}
 	}
+	c.rootCommandeer.logger.DebugWith("Sampled rows for schema", "rows", inferrer.Rows())
 
+	newSchema := inferrer.Schema(c.hashingBuckets)
 	bytes, _ := newSchema.ToJson()
-	fmt.Fprintln(out, string(bytes))
+	fmt.Fprintln(c.rootCommandeer.out, string(bytes))
 
-	err = nullSchema.UpdateSchema(container, endWithSlash(c.rootCommandeer.dirPath), newSchema)
-	if err != nil {
-		return err
+	if c.dryRun {
+		return nil
 	}
 
-	return nil
+	return newSchema.Save(container, endWithSlash(c.rootCommandeer.dirPath))
+}
+
+func sampleFirst(iter *utils.AsyncItemsCursor, maxrec int) []map[string]interface{} {
+	rowSet := []map[string]interface{}{}
+	for len(rowSet) < maxrec && iter.Next() {
+		rowSet = append(rowSet, iter.GetFields())
+	}
+	return rowSet
+}
+
+// take an equal share of the rows from every scan segment
+func sampleStratified(iter *utils.AsyncItemsCursor, maxrec int) []map[string]interface{} {
+	segments := iter.TotalSegments()
+	if segments <= 1 {
+		return sampleFirst(iter, maxrec)
+	}
+
+	quota := (maxrec + segments - 1) / segments
+	perSegment := make([]int, segments)
+	rowSet := []map[string]interface{}{}
+	for len(rowSet) < maxrec && iter.Next() {
+		segment := iter.GetSegment()
+		if perSegment[segment] < quota {
+			perSegment[segment]++
+			rowSet = append(rowSet, iter.GetFields())
+		}
+	}
+	return rowSet
+}
+
+// reservoir sampling over the entire table
+func sampleRandom(iter *utils.AsyncItemsCursor, maxrec int) []map[string]interface{} {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rowSet := []map[string]interface{}{}
+	seen := 0
+	for iter.Next() {
+		seen++
+		if len(rowSet) < maxrec {
+			rowSet = append(rowSet, iter.GetFields())
+		} else if i := random.Intn(seen); i < maxrec {
+			rowSet[i] = iter.GetFields()
+		}
+	}
+	return rowSet
 }
@@ -21,12 +21,19 @@ such restriction.
 package commands
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/v3io/v3cli/pkg/utils"
 	"github.com/v3io/v3io-go-http"
+	"io"
 	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
 )
 
 type getItemsCommandeer struct {
@@ -35,6 +42,11 @@ type getItemsCommandeer struct {
 	attributes     []string
 	filter         string
 	maxrec         int
+	shardingKeys   []string
+	sortFrom       string
+	sortTo         string
+	typed          bool
+	decodeBlob     string
 }
 
 func NewCmdGetitems(rootCommandeer *RootCommandeer) *getItemsCommandeer {
@@ -44,7 +56,7 @@ func NewCmdGetitems(rootCommandeer *RootCommandeer) *getItemsCommandeer {
 	}
 
 	cmd := &cobra.Command{
-		Use:     "getitems [container-name] [table-path] [-a attrs] [-q query]",
+		Use:     "getitems [container-name] [table-path] [-a attrs] [-q query] [-k sharding-keys]",
 		Short:   "Retrive multiple records and fields (as json struct) based on query",
 		Aliases: []string{"gis"},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -56,6 +68,13 @@ func NewCmdGetitems(rootCommandeer *RootCommandeer) *getItemsCommandeer {
 	cmd.Flags().StringSliceVarP(&commandeer.attributes, "attrs", "a", []string{"*"}, "GetItem(s) Columns to return seperated by ','")
 	cmd.Flags().StringVarP(&commandeer.filter, "filter", "q", "", "GetItems query filter string, see getitems help for more")
 	cmd.Flags().IntVarP(&commandeer.maxrec, "max-rec", "m", 50, "Max Records/Items to get per call")
+	cmd.Flags().StringSliceVarP(&commandeer.shardingKeys, "sharding-key", "k", []string{},
+		"Read only these sharding keys (in parallel) seperated by ',', for tables keyed as <sharding-key>.<sorting-key>")
+	cmd.Flags().StringVar(&commandeer.sortFrom, "sort-from", "",
+		"Minimal sorting key (inclusive), requires --sharding-key. Keys are compared as strings,\nso numeric keys need a fixed width (e.g. 0009 < 0010)")
+	cmd.Flags().StringVar(&commandeer.sortTo, "sort-to", "",
+		"Maximal sorting key (inclusive), requires --sharding-key, compared as a string like --sort-from")
+	addItemOutputFlags(cmd, &commandeer.typed, &commandeer.decodeBlob)
 
 	commandeer.cmd = cmd
 
@@ -64,6 +83,21 @@ func NewCmdGetitems(rootCommandeer *RootCommandeer) *getItemsCommandeer {
 
 func (c *getItemsCommandeer) getitems() error {
 
+	if err := validateBlobMode(c.decodeBlob, c.typed); err != nil {
+		return err
+	}
+
+	var sortKeyRange *utils.SortKeyRange
+	if c.sortFrom != "" || c.sortTo != "" {
+		if len(c.shardingKeys) == 0 {
+			return fmt.Errorf("--sort-from/--sort-to require --sharding-key")
+		}
+		if strings.Contains(c.sortFrom+c.sortTo+strings.Join(c.shardingKeys, ""), "'") {
+			return fmt.Errorf("sharding and sorting keys with quotes are not supported in a range scan")
+		}
+		sortKeyRange = &utils.SortKeyRange{Start: c.sortFrom, End: c.sortTo}
+	}
+
 	if err := c.rootCommandeer.initialize(); err != nil {
 		return err
 	}
@@ -74,9 +108,9 @@ func (c *getItemsCommandeer) getitems() error {
 	}
 
 	input := v3io.GetItemsInput{Path: endWithSlash(c.rootCommandeer.dirPath), Filter: c.filter, AttributeNames: c.attributes}
-	c.rootCommandeer.logger.DebugWith("GetItems input", "input", input)
-	iter, err := utils.NewAsyncItemsCursor(
-		container, &input, c.rootCommandeer.v3iocfg.QryWorkers, []string{}, c.rootCommandeer.logger, 0)
+	c.rootCommandeer.logger.DebugWith("GetItems input", "input", input, "shardingKeys", c.shardingKeys)
+	iter, err := utils.NewAsyncItemsCursorWithRange(
+		container, &input, c.rootCommandeer.v3iocfg.QryWorkers, c.shardingKeys, sortKeyRange, c.rootCommandeer.logger, 0)
 	if err != nil {
 		return err
 	}
@@ -86,7 +120,10 @@ func (c *getItemsCommandeer) getitems() error {
 	first := true
 
 	for rowNum := 0; rowNum < c.maxrec && iter.Next(); rowNum++ {
-		row := iter.GetFields()
+		row, err := utils.FormatItem(iter.GetFields(), c.typed, c.decodeBlob)
+		if err != nil {
+			return err
+		}
 		body, err := json.Marshal(row)
 		if err != nil {
 			return err
@@ -110,8 +147,15 @@ type putItemCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
 	condition      string
+	types          []string
+	validate       bool
+	strict         bool
 }
 
+const PutItemExamples string = `   echo '{"name": "joe", "age": 30}' | v3ctl putitem datalake users/joe
+   echo '{"age": {"N": "30"}, "img": {"B": "aGVsbG8="}}' | v3ctl putitem datalake users/joe
+   echo '{"age": "30", "joined": "2018-06-01T10:00:00Z"}' | v3ctl putitem datalake users/joe -t age=int,joined=time`
+
 func NewCmdPutitem(rootCommandeer *RootCommandeer) *putItemCommandeer {
 
 	commandeer := &putItemCommandeer{
@@ -121,9 +165,15 @@ func NewCmdPutitem(rootCommandeer *RootCommandeer) *putItemCommandeer {
 	cmd := &cobra.Command{
 		Use:     "putitem [container-name] [table-path/key]",
 		Short:   "Upload record content/fields from json input file or stdin",
+		Example: PutItemExamples,
 		Aliases: []string{"pi"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 
+			types, err := utils.ParseTypeHints(commandeer.types)
+			if err != nil {
+				return err
+			}
+
 			bytes, err := ioutil.ReadAll(commandeer.rootCommandeer.in)
 			if err != nil {
 				return fmt.Errorf("Error reading input file (%v)\n", err)
@@ -139,18 +189,30 @@ func NewCmdPutitem(rootCommandeer *RootCommandeer) *putItemCommandeer {
 				return err
 			}
 
-			list := make(map[string]interface{})
-			err = json.Unmarshal(bytes, &list)
+			list, err := utils.DecodeItemJson(bytes, types)
 			if err != nil {
 				return fmt.Errorf("failed to unmarshal results (%v)", err)
 			}
 
+			if commandeer.validate || commandeer.strict {
+				schema, err := loadItemSchema(container, root.dirPath)
+				if err != nil {
+					return err
+				}
+				if err := schema.Validate(list, commandeer.strict); err != nil {
+					return err
+				}
+			}
+
 			return container.Sync.PutItem(&v3io.PutItemInput{
 				Path: root.dirPath, Attributes: list, Condition: commandeer.condition})
 		},
 	}
 	cmd.Flags().StringVarP(&rootCommandeer.inFile, "input-file", "f", "", "Input file for the different put* commands")
 	cmd.Flags().StringVarP(&commandeer.condition, "condition", "n", "", "Update condition, update only if the condition is met")
+	cmd.Flags().StringSliceVarP(&commandeer.types, "types", "t", []string{},
+		"Attribute type hints seperated by ',', e.g. age=int,img=blob,ts=time\n(int | float | string | blob (base64) | time (stored as epoch nanoseconds))")
+	addValidateFlags(cmd, &commandeer.validate, &commandeer.strict)
 
 	commandeer.cmd = cmd
 	return commandeer
@@ -161,6 +223,8 @@ type updateItemCommandeer struct {
 	rootCommandeer *RootCommandeer
 	expression     string
 	condition      string
+	validate       bool
+	strict         bool
 }
 
 func NewCmdUpdateItem(rootCommandeer *RootCommandeer) *updateItemCommandeer {
@@ -185,6 +249,16 @@ func NewCmdUpdateItem(rootCommandeer *RootCommandeer) *updateItemCommandeer {
 				return err
 			}
 
+			if commandeer.validate || commandeer.strict {
+				schema, err := loadItemSchema(container, root.dirPath)
+				if err != nil {
+					return err
+				}
+				if err := schema.ValidateUpdate(commandeer.expression, commandeer.strict); err != nil {
+					return err
+				}
+			}
+
 			//TODO: add condition (to v3io-http)
 			return container.Sync.UpdateItem(&v3io.UpdateItemInput{
 				Path: root.dirPath, Expression: &commandeer.expression, Condition: commandeer.condition})
@@ -193,15 +267,112 @@ func NewCmdUpdateItem(rootCommandeer *RootCommandeer) *updateItemCommandeer {
 
 	cmd.Flags().StringVarP(&commandeer.expression, "expression", "e", "", "Update expression, e.g. x=5;y='good';z=z+1")
 	cmd.Flags().StringVarP(&commandeer.condition, "condition", "n", "", "Update condition, update only if the condition is met")
+	addValidateFlags(cmd, &commandeer.validate, &commandeer.strict)
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func addValidateFlags(cmd *cobra.Command, validate *bool, strict *bool) {
+	cmd.Flags().BoolVar(validate, "validate", false,
+		"Validate attribute types and non-nullable attributes against the table schema (.#schema) before writing")
+	cmd.Flags().BoolVar(strict, "strict", false, "Validate against the table schema and reject attributes not in the schema")
+}
+
+// load the schema of the table holding the item
+func loadItemSchema(container *v3io.Container, itemPath string) (utils.V3ioSchema, error) {
+	table := path.Dir(strings.TrimSuffix(itemPath, "/"))
+	if table == "." || table == "/" {
+		return nil, fmt.Errorf("item path must be of the form <table-path>/<key> to validate")
+	}
+
+	return utils.LoadSchema(container, endWithSlash(table))
+}
+
+type updateItemsCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	filter         string
+	expression     string
+	condition      string
+	recheck        bool
+}
+
+func NewCmdUpdateItems(rootCommandeer *RootCommandeer) *updateItemsCommandeer {
+
+	commandeer := &updateItemsCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "updateitems [container-name] [table-path] [-q query] [-e expression]",
+		Short:   "update multiple records matching a filter using an expression (and optional condition)",
+		Aliases: []string{"uis"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.updateitems()
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.filter, "filter", "q", "", "GetItems query filter string, see getitems help for more")
+	cmd.Flags().StringVarP(&commandeer.expression, "expression", "e", "", "Update expression, e.g. x=5;y='good';z=z+1")
+	cmd.Flags().StringVarP(&commandeer.condition, "condition", "n", "", "Update condition, update only if the condition is met")
+	cmd.Flags().BoolVarP(&commandeer.recheck, "recheck", "r", false,
+		"Re-check the filter as part of the update condition (skip items changed since the scan)")
 
 	commandeer.cmd = cmd
 	return commandeer
 }
 
+func (c *updateItemsCommandeer) updateitems() error {
+
+	if c.expression == "" {
+		return fmt.Errorf("missing update expression (-e)")
+	}
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	condition := c.condition
+	if c.recheck && c.filter != "" {
+		if condition == "" {
+			condition = c.filter
+		} else {
+			condition = fmt.Sprintf("(%s) and (%s)", c.filter, condition)
+		}
+	}
+
+	result, err := utils.UpdateItems(
+		root.logger, container, endWithSlash(root.dirPath), c.filter, c.expression, condition, root.v3iocfg.QryWorkers)
+	if result != nil {
+		fmt.Fprintf(root.out, "Updated: %d, Skipped: %d, Failed: %d\n", result.Updated, result.Skipped, result.Failed)
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.Failed > 0 {
+		return fmt.Errorf("Failed to update %d items", result.Failed)
+	}
+
+	return nil
+}
+
 type getItemCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
 	attributes     []string
+	keysFrom       string
+	unordered      bool
+	typed          bool
+	decodeBlob     string
 }
 
 func NewCmdGetitem(rootCommandeer *RootCommandeer) *getItemCommandeer {
@@ -211,11 +382,15 @@ func NewCmdGetitem(rootCommandeer *RootCommandeer) *getItemCommandeer {
 	}
 
 	cmd := &cobra.Command{
-		Use:     "getitem [container-name] [table-path/key]",
+		Use:     "getitem [container-name] [table-path/key] [--keys-from -|file]",
 		Short:   "Retrive record content/fields (as json struct)",
 		Aliases: []string{"gi"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 
+			if err := validateBlobMode(commandeer.decodeBlob, commandeer.typed); err != nil {
+				return err
+			}
+
 			root := commandeer.rootCommandeer
 			if err := root.initialize(); err != nil {
 				return err
@@ -226,6 +401,10 @@ func NewCmdGetitem(rootCommandeer *RootCommandeer) *getItemCommandeer {
 				return err
 			}
 
+			if commandeer.keysFrom != "" {
+				return commandeer.getitemsByKeys(container)
+			}
+
 			input := v3io.GetItemInput{Path: commandeer.rootCommandeer.dirPath, AttributeNames: commandeer.attributes}
 			resp, err := container.Sync.GetItem(&input)
 			if err != nil {
@@ -233,7 +412,11 @@ func NewCmdGetitem(rootCommandeer *RootCommandeer) *getItemCommandeer {
 			}
 			output := resp.Output.(*v3io.GetItemOutput)
 
-			body, err := json.Marshal(output.Item)
+			item, err := utils.FormatItem(output.Item, commandeer.typed, commandeer.decodeBlob)
+			if err != nil {
+				return err
+			}
+			body, err := json.Marshal(item)
 			if err != nil {
 				return err
 			}
@@ -243,17 +426,160 @@ func NewCmdGetitem(rootCommandeer *RootCommandeer) *getItemCommandeer {
 		},
 	}
 	cmd.Flags().StringSliceVarP(&commandeer.attributes, "attrs", "a", []string{"*"}, "GetItem(s) Columns to return seperated by ','")
+	cmd.Flags().StringVarP(&commandeer.keysFrom, "keys-from", "k", "",
+		"Read item keys (one per line) from a file or '-' for stdin, and get them from the table path in parallel")
+	cmd.Flags().BoolVar(&commandeer.unordered, "unordered", false,
+		"With --keys-from, write items as they arrive rather than in input order")
+	addItemOutputFlags(cmd, &commandeer.typed, &commandeer.decodeBlob)
 
 	commandeer.cmd = cmd
 
 	return commandeer
 }
 
+type getItemRequest struct {
+	index int
+	key   string
+}
+
+// get every key read from the keys file in parallel, items are written as NDJSON and missing keys reported to stderr
+func (c *getItemCommandeer) getitemsByKeys(container *v3io.Container) error {
+
+	root := c.rootCommandeer
+	var in io.Reader = os.Stdin
+	if c.keysFrom != "-" {
+		file, err := os.Open(c.keysFrom)
+		if err != nil {
+			return fmt.Errorf("Failed to open keys file: %s\n", err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	tablePath := endWithSlash(root.dirPath)
+	results := map[int][]byte{}
+	missing := []string{}
+	failed := 0
+	next := 0
+
+	// write the results which are next in order, a nil result marks a key without an item
+	flush := func() {
+		for {
+			line, ok := results[next]
+			if !ok {
+				return
+			}
+			if line != nil {
+				fmt.Fprintf(root.out, "%s\n", line)
+			}
+			delete(results, next)
+			next++
+		}
+	}
+
+	responseChan := make(chan *v3io.Response, 1000)
+	commChan := make(chan int, 2)
+	doneChan := utils.WaitResponses(commChan, responseChan, func(resp *v3io.Response) {
+		request := resp.Context.(*getItemRequest)
+		var line []byte
+
+		if resp.Error != nil {
+			if utils.IsNotFound(resp.Error) {
+				missing = append(missing, request.key)
+			} else {
+				failed++
+				fmt.Fprintf(os.Stderr, "Failed to get item '%s' - %v\n", request.key, resp.Error)
+			}
+		} else {
+			item := resp.Output.(*v3io.GetItemOutput).Item
+			if _, ok := item["__name"]; !ok {
+				item["__name"] = request.key
+			}
+
+			formatted, err := utils.FormatItem(item, c.typed, c.decodeBlob)
+			if err == nil {
+				line, err = json.Marshal(formatted)
+			}
+			if err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "Failed to marshal item '%s' - %v\n", request.key, err)
+				line = nil
+			}
+		}
+
+		if c.unordered {
+			if line != nil {
+				fmt.Fprintf(root.out, "%s\n", line)
+			}
+			return
+		}
+
+		results[request.index] = line
+		flush()
+	})
+
+	requests := 0
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" {
+			continue
+		}
+
+		input := v3io.GetItemInput{Path: tablePath + url.QueryEscape(key), AttributeNames: c.attributes}
+		_, err := container.GetItem(&input, &getItemRequest{index: requests, key: key}, responseChan)
+		if err != nil {
+			commChan <- requests
+			<-doneChan
+			return fmt.Errorf("Error in GetItem operation (%v)", err)
+		}
+		requests++
+	}
+
+	commChan <- requests
+	<-doneChan
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading keys (%v)", err)
+	}
+
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "%d keys not found:\n%s\n", len(missing), strings.Join(missing, "\n"))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to get %d of %d items", failed, requests)
+	}
+
+	return nil
+}
+
+func addItemOutputFlags(cmd *cobra.Command, typed *bool, decodeBlob *string) {
+	cmd.Flags().BoolVar(typed, "typed", false,
+		"Tag every value with its v3io type, e.g. {\"age\": {\"N\": \"30\"}} (can be used as putitem input)")
+	cmd.Flags().StringVar(decodeBlob, "decode-blob", "", "Decode blob values [int64array | utf8 | hex]")
+}
+
+func validateBlobMode(mode string, typed bool) error {
+	if mode == "" {
+		return nil
+	}
+	// typed blobs must stay base64 to be read back by putitem
+	if typed {
+		return fmt.Errorf("--decode-blob can't be used with --typed")
+	}
+	_, err := utils.DecodeBlob([]byte{}, mode)
+	return err
+}
+
 type delItemsCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
 	filter         string
 	force          bool
+	dryRun         bool
+	backup         string
+	sampleKeys     int
 }
 
 func NewCmdDelitems(rootCommandeer *RootCommandeer) *delItemsCommandeer {
@@ -268,37 +594,136 @@ func NewCmdDelitems(rootCommandeer *RootCommandeer) *delItemsCommandeer {
 		Aliases: []string{"gis"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 
-			root := commandeer.rootCommandeer
-			if err := root.initialize(); err != nil {
-				return err
-			}
-
-			container, err := root.initV3io()
-			if err != nil {
-				return err
-			}
-
-			if !commandeer.force {
-				confirmedByUser, err := getConfirmation(
-					fmt.Sprintf("You are about to delete the table '%s' in container '%s'. Are you sure?", root.dirPath, root.container))
-				if err != nil {
-					return err
-				}
-
-				if !confirmedByUser {
-					return fmt.Errorf("Delete cancelled by the user.")
-				}
-			}
-
-			return utils.DeleteTable(root.logger, container, endWithSlash(root.dirPath), commandeer.filter, root.v3iocfg.Workers)
+			return commandeer.delitems()
 		},
 	}
 
 	cmd.Flags().StringVarP(&commandeer.filter, "filter", "q", "", "GetItems query filter string, see getitems help for more")
 	cmd.Flags().BoolVarP(&commandeer.force, "force", "f", false,
 		"Forceful deletion - don't display a delete-verification prompt.")
+	cmd.Flags().BoolVar(&commandeer.dryRun, "dry-run", false, "Show the number of matching records and sample keys without deleting")
+	cmd.Flags().StringVar(&commandeer.backup, "backup", "",
+		"Save the records (as typed NDJSON, see getitem --typed) to this file before deleting them")
+	cmd.Flags().IntVar(&commandeer.sampleKeys, "sample-keys", 10, "Number of sample keys to show with --dry-run")
 
 	commandeer.cmd = cmd
 
 	return commandeer
 }
+
+func (c *delItemsCommandeer) delitems() error {
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	tablePath := endWithSlash(root.dirPath)
+	if c.dryRun {
+		return c.preview(container, tablePath)
+	}
+
+	if !c.force {
+		prompt := fmt.Sprintf("You are about to delete the table '%s' in container '%s'. Are you sure?", root.dirPath, root.container)
+		if c.filter != "" {
+			prompt = fmt.Sprintf("You are about to delete the records matching '%s' in table '%s' in container '%s'. Are you sure?",
+				c.filter, root.dirPath, root.container)
+		}
+
+		confirmedByUser, err := getConfirmation(prompt)
+		if err != nil {
+			return err
+		}
+
+		if !confirmedByUser {
+			return fmt.Errorf("Delete cancelled by the user.")
+		}
+	}
+
+	// the backup is written unbuffered, so every item is in the file before it's deleted
+	var backup io.Writer
+	var backupFile *os.File
+	if c.backup != "" {
+		backupFile, err = os.OpenFile(c.backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			return fmt.Errorf("Failed to create backup file: %s\n", err)
+		}
+		backup = backupFile
+	}
+
+	lastReport := time.Now()
+	reported, deleted, failedCount := false, 0, 0
+	progress := func(d, f int) {
+		deleted, failedCount = d, f
+		if time.Since(lastReport) >= time.Second {
+			lastReport = time.Now()
+			reported = true
+			fmt.Fprintf(os.Stderr, "\rDeleted %d records, %d failed", deleted, failedCount)
+		}
+	}
+
+	failed, err := utils.DeleteItems(root.logger, container, tablePath, c.filter, root.v3iocfg.Workers, backup, progress)
+	if reported {
+		fmt.Fprintf(os.Stderr, "\rDeleted %d records, %d failed\n", deleted, failedCount)
+	}
+
+	if backupFile != nil {
+		syncErr := backupFile.Sync()
+		closeErr := backupFile.Close()
+		if err == nil && syncErr != nil {
+			err = fmt.Errorf("Failed to sync backup file: %s", syncErr)
+		}
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("Failed to close backup file: %s", closeErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to delete %d records:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+
+	fmt.Fprintln(root.out, "Delete completed")
+	return nil
+}
+
+// count the records matching the filter and show some of their keys
+func (c *delItemsCommandeer) preview(container *v3io.Container, tablePath string) error {
+
+	root := c.rootCommandeer
+	input := v3io.GetItemsInput{Path: tablePath, AttributeNames: []string{"__name"}, Filter: c.filter}
+	iter, err := utils.NewAsyncItemsCursor(container, &input, root.v3iocfg.QryWorkers, []string{}, root.logger, 0)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	keys := []string{}
+	for iter.Next() {
+		if len(keys) < c.sampleKeys {
+			keys = append(keys, fmt.Sprint(iter.GetField("__name")))
+		}
+		count++
+	}
+
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+
+	fmt.Fprintf(root.out, "%d records would be deleted\n", count)
+	for _, key := range keys {
+		fmt.Fprintf(root.out, "  %s\n", key)
+	}
+	if count > len(keys) {
+		fmt.Fprintf(root.out, "  ...\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// write result rows as an aligned table, csv or a json array of objects
+func writeRows(out io.Writer, format string, columns []string, rows [][]interface{}) error {
+	switch strings.ToLower(format) {
+	case "table", "":
+		return writeTable(out, columns, rows)
+	case "csv":
+		return writeCSV(out, columns, rows)
+	case "json":
+		return writeJSONRows(out, columns, rows)
+	}
+
+	return validateFormat(format, "table", "csv", "json")
+}
+
+// return an error unless the output format is one of the supported formats
+func validateFormat(format string, formats ...string) error {
+	for _, f := range formats {
+		if strings.ToLower(format) == f {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Output format %s is invalid, use %s", format, strings.Join(formats, " | "))
+}
+
+func writeTable(out io.Writer, columns []string, rows [][]interface{}) error {
+	widths := make([]int, len(columns))
+	cells := make([][]string, len(rows))
+	for i, col := range columns {
+		widths[i] = len(col)
+	}
+	for r, row := range rows {
+		cells[r] = make([]string, len(row))
+		for i, val := range row {
+			cells[r][i] = formatValue(val)
+			if len(cells[r][i]) > widths[i] {
+				widths[i] = len(cells[r][i])
+			}
+		}
+	}
+
+	writeLine := func(line []string) {
+		for i, cell := range line {
+			if i == len(line)-1 {
+				fmt.Fprintf(out, "%s\n", cell)
+			} else {
+				fmt.Fprintf(out, "%-*s  ", widths[i], cell)
+			}
+		}
+	}
+
+	writeLine(columns)
+	for _, line := range cells {
+		writeLine(line)
+	}
+
+	return nil
+}
+
+func writeCSV(out io.Writer, columns []string, rows [][]interface{}) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		line := make([]string, len(row))
+		for i, val := range row {
+			line[i] = formatValue(val)
+		}
+		if err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSONRows(out io.Writer, columns []string, rows [][]interface{}) error {
+	fmt.Fprintf(out, "[\n")
+	defer fmt.Fprintf(out, "\n]\n")
+	for r, row := range rows {
+		body, err := marshalOrdered(columns, row)
+		if err != nil {
+			return err
+		}
+		if r > 0 {
+			fmt.Fprintf(out, ",\n")
+		}
+		fmt.Fprintf(out, "%s", body)
+	}
+
+	return nil
+}
+
+// marshal a row as a json object, keeping the column order
+func marshalOrdered(columns []string, row []interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i, col := range columns {
+		key, err := json.Marshal(col)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(row[i])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.Write(key)
+		buffer.WriteString(":")
+		buffer.Write(val)
+	}
+	buffer.WriteString("}")
+
+	return buffer.Bytes(), nil
+}
+
+func formatValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(val)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"sort"
+	"strings"
+)
+
+const ProfileExamples string = `   v3ctl profile datalake mytable
+   v3ctl profile datalake mytable -a age,city -q "age>30" --top 10 -o json`
+
+type profileCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	attributes     []string
+	filter         string
+	topK           int
+	output         string
+}
+
+func NewCmdProfile(rootCommandeer *RootCommandeer) *profileCommandeer {
+
+	commandeer := &profileCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "profile [container-name] [table-path] [-a attrs] [-q query] [--top k]",
+		Short:   "Show per attribute statistics (types, missing, min/max, mean/stddev, distinct, top values)",
+		Example: ProfileExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.profile()
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&commandeer.attributes, "attrs", "a", []string{"*"}, "Columns to profile seperated by ','")
+	cmd.Flags().StringVarP(&commandeer.filter, "filter", "q", "", "GetItems query filter string, see getitems help for more")
+	cmd.Flags().IntVar(&commandeer.topK, "top", 5, "Number of most frequent values to show per attribute (0 to disable)")
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", "table", "Output format [table | csv | json]")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (c *profileCommandeer) profile() error {
+
+	if err := validateFormat(c.output, "table", "csv", "json"); err != nil {
+		return err
+	}
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	input := v3io.GetItemsInput{Path: endWithSlash(root.dirPath), Filter: c.filter, AttributeNames: c.attributes}
+	root.logger.DebugWith("GetItems for profile", "input", input)
+	iter, err := utils.NewAsyncItemsCursor(container, &input, root.v3iocfg.QryWorkers, []string{}, root.logger, 0)
+	if err != nil {
+		return err
+	}
+
+	profiler := utils.NewProfiler(c.topK)
+	for iter.Next() {
+		profiler.Add(iter.GetFields())
+	}
+
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+
+	profiles := profiler.Profiles()
+	if strings.ToLower(c.output) == "json" {
+		body, err := json.MarshalIndent(map[string]interface{}{"rows": profiler.Rows(), "attributes": profiles}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(root.out, string(body))
+		return nil
+	}
+
+	columns := []string{"attribute", "types", "count", "missing", "min", "max", "mean", "stddev", "distinct", "top"}
+	rows := make([][]interface{}, len(profiles))
+	for i, attr := range profiles {
+		rows[i] = []interface{}{attr.Name, formatTypes(attr.Types), attr.Count, attr.Missing,
+			attr.Min, attr.Max, floatOrNil(attr.Mean), floatOrNil(attr.Stddev), attr.Distinct, formatTop(attr.Top)}
+	}
+
+	if err := writeRows(root.out, c.output, columns, rows); err != nil {
+		return err
+	}
+	if strings.ToLower(c.output) == "table" {
+		fmt.Fprintf(root.out, "Rows: %d\n", profiler.Rows())
+	}
+	return nil
+}
+
+// format type counts as long(10) string(2), most common first
+func formatTypes(types map[string]int) string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if types[names[i]] != types[names[j]] {
+			return types[names[i]] > types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s(%d)", name, types[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatTop(top []utils.ValueCount) string {
+	parts := make([]string, len(top))
+	for i, vc := range top {
+		parts[i] = fmt.Sprintf("%s(%d)", vc.Value, vc.Count)
+	}
+	return strings.Join(parts, " ")
+}
+
+func floatOrNil(val *float64) interface{} {
+	if val == nil {
+		return nil
+	}
+	return *val
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"sort"
+	"strings"
+)
+
+const QueryExamples string = `   v3ctl query "SELECT * FROM datalake.mytable WHERE age > 30"
+   v3ctl query "SELECT name, age FROM datalake.users/active WHERE city = 'NY' ORDER BY age DESC LIMIT 10" -o table`
+
+type queryCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	output         string
+}
+
+func NewCmdQuery(rootCommandeer *RootCommandeer) *queryCommandeer {
+
+	commandeer := &queryCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "query \"SELECT attrs FROM container.table [WHERE expr] [ORDER BY attrs] [LIMIT n]\"",
+		Short:   "Retrive records using a SQL SELECT statement",
+		Example: QueryExamples,
+		Aliases: []string{"sql"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.query(args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", "json", "Output format [json | table | csv]")
+
+	commandeer.cmd = cmd
+
+	return commandeer
+}
+
+func (c *queryCommandeer) query(sql string) error {
+
+	c.output = strings.ToLower(c.output)
+	if err := validateFormat(c.output, "json", "table", "csv"); err != nil {
+		return err
+	}
+
+	query, err := utils.ParseSelect(sql)
+	if err != nil {
+		return fmt.Errorf("Failed to parse query - %v", err)
+	}
+
+	// the container and table are taken from the FROM clause rather than the command args
+	root := c.rootCommandeer
+	root.container = query.Container
+	root.dirPath = query.Table
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	// ORDER BY attributes must be fetched even when not selected
+	columns := query.Attributes
+	allColumns := false
+	attrs := append([]string{}, columns...)
+	for _, attr := range columns {
+		if attr == "*" {
+			allColumns = true
+			attrs = []string{"*"}
+		}
+	}
+	if !allColumns {
+		for _, field := range query.OrderBy {
+			if !containsString(attrs, field.Attribute) {
+				attrs = append(attrs, field.Attribute)
+			}
+		}
+	}
+
+	input := v3io.GetItemsInput{Path: endWithSlash(root.dirPath), Filter: query.Filter, AttributeNames: attrs}
+	root.logger.DebugWith("GetItems for query", "input", input)
+	iter, err := utils.NewAsyncItemsCursor(container, &input, root.v3iocfg.QryWorkers, []string{}, root.logger, 0)
+	if err != nil {
+		return err
+	}
+
+	// without ORDER BY rows are written as they are read and LIMIT stops the scan early
+	streaming := len(query.OrderBy) == 0 && c.output == "json"
+	out := root.out
+	rows := []map[string]interface{}{}
+	count := 0
+
+	// the array is closed on errors too, so the output stays valid json
+	if streaming {
+		fmt.Fprintf(out, "[\n")
+		defer fmt.Fprintf(out, "\n]\n")
+	}
+	for iter.Next() {
+		if len(query.OrderBy) == 0 && query.Limit > 0 && count >= query.Limit {
+			break
+		}
+		if streaming {
+			if err := c.writeJSONRow(columns, allColumns, iter.GetFields(), count == 0); err != nil {
+				return err
+			}
+		} else {
+			rows = append(rows, iter.GetFields())
+		}
+		count++
+	}
+
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+
+	if streaming {
+		return nil
+	}
+
+	if len(query.OrderBy) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, field := range query.OrderBy {
+				cmp := utils.CompareValues(rows[i][field.Attribute], rows[j][field.Attribute])
+				if field.Descending {
+					cmp = -cmp
+				}
+				if cmp != 0 {
+					return cmp < 0
+				}
+			}
+			return false
+		})
+		if query.Limit > 0 && len(rows) > query.Limit {
+			rows = rows[:query.Limit]
+		}
+	}
+
+	if c.output == "json" {
+		fmt.Fprintf(out, "[\n")
+		defer fmt.Fprintf(out, "\n]\n")
+		for i, row := range rows {
+			if err := c.writeJSONRow(columns, allColumns, row, i == 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if allColumns {
+		columns = rowsColumns(rows)
+	}
+	table := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		table[i] = make([]interface{}, len(columns))
+		for j, col := range columns {
+			table[i][j] = row[col]
+		}
+	}
+
+	return writeRows(out, c.output, columns, table)
+}
+
+func (c *queryCommandeer) writeJSONRow(columns []string, allColumns bool, row map[string]interface{}, first bool) error {
+	var body []byte
+	var err error
+	if allColumns {
+		body, err = json.Marshal(row)
+	} else {
+		values := make([]interface{}, len(columns))
+		for i, col := range columns {
+			values[i] = row[col]
+		}
+		body, err = marshalOrdered(columns, values)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !first {
+		fmt.Fprintf(c.rootCommandeer.out, ",\n")
+	}
+	fmt.Fprintf(c.rootCommandeer.out, "%s", body)
+	return nil
+}
+
+// return the sorted union of the attribute names in all rows
+func rowsColumns(rows []map[string]interface{}) []string {
+	seen := map[string]bool{}
+	columns := []string{}
+	for _, row := range rows {
+		for name := range row {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	return columns
+}
+
+func containsString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
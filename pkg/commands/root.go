@@ -126,13 +126,28 @@ func NewRootCommandeer() *RootCommandeer {
 		NewCmdDel(commandeer).cmd,
 		NewCmdPutitem(commandeer).cmd,
 		NewCmdUpdateItem(commandeer).cmd,
+		NewCmdUpdateItems(commandeer).cmd,
+		NewCmdEditItem(commandeer).cmd,
 		NewCmdGetitem(commandeer).cmd,
 		NewCmdGetitems(commandeer).cmd,
 		NewCmdGetrecord(commandeer).cmd,
 		NewCmdPutrecord(commandeer).cmd,
+		NewCmdPutrecords(commandeer).cmd,
 		NewCmdDelitems(commandeer).cmd,
+		NewCmdCount(commandeer).cmd,
+		NewCmdAggregate(commandeer).cmd,
+		NewCmdQuery(commandeer).cmd,
+		NewCmdDiffTable(commandeer).cmd,
+		NewCmdProfile(commandeer).cmd,
+		NewCmdConsume(commandeer).cmd,
 		NewCmdCreatestream(commandeer).cmd,
+		NewCmdDeletestream(commandeer).cmd,
+		NewCmdDescribestream(commandeer).cmd,
+		NewCmdStream2KV(commandeer).cmd,
+		NewCmdCopyStream(commandeer).cmd,
+		NewCmdStreamStat(commandeer).cmd,
 		NewCmdInferSchema(commandeer).cmd,
+		NewCmdSchema(commandeer).cmd,
 		NewCmdComplete(commandeer),
 		NewCmdBash(),
 		NewCmdIngest(commandeer),
@@ -268,7 +283,15 @@ func (rc *RootCommandeer) initV3io() (*v3io.Container, error) {
 		rc.container = rc.v3iocfg.Container
 	}
 
-	rc.logger, _ = utils.NewLogger(rc.v3iocfg.LogLevel)
+	return rc.openContainer(rc.container)
+}
+
+// open a data container with the loaded configuration (see initialize), without changing the root container
+func (rc *RootCommandeer) openContainer(name string) (*v3io.Container, error) {
+
+	if rc.logger == nil {
+		rc.logger, _ = utils.NewLogger(rc.v3iocfg.LogLevel)
+	}
 
 	config := v3io.SessionConfig{
 		Username:   rc.v3iocfg.Username,
@@ -277,7 +300,7 @@ func (rc *RootCommandeer) initV3io() (*v3io.Container, error) {
 		SessionKey: rc.v3iocfg.SessionKey}
 
 	newContainer, err := utils.CreateContainer(
-		rc.logger, rc.v3iocfg.WebApiEndpoint, rc.container, &config, rc.v3iocfg.Workers)
+		rc.logger, rc.v3iocfg.WebApiEndpoint, name, &config, rc.v3iocfg.Workers)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to initialize a data container.")
 	}
@@ -0,0 +1,347 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"io/ioutil"
+	"strings"
+)
+
+const SchemaExamples string = `   v3ctl schema show datalake mytable -o yaml
+   v3ctl schema diff datalake mytable -f schema.yaml
+   v3ctl schema set datalake mytable -f schema.json
+   v3ctl schema add-column datalake mytable --name price --type double --nullable
+   v3ctl schema drop-column datalake mytable --name price
+   v3ctl schema set-key datalake mytable --key id`
+
+type schemaCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	container      *v3io.Container
+	output         string
+	force          bool
+	name           string
+	ftype          string
+	nullable       bool
+	key            string
+}
+
+func NewCmdSchema(rootCommandeer *RootCommandeer) *schemaCommandeer {
+
+	commandeer := &schemaCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "schema [show | set | diff | add-column | drop-column | set-key] [container-name] [table-path]",
+		Short:   "Show or change the table schema (.#schema)",
+		Example: SchemaExamples,
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show [container-name] [table-path] [-o json|yaml]",
+		Short: "Show the table schema",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			schema, err := commandeer.load()
+			if err != nil {
+				return err
+			}
+			return commandeer.print(schema)
+		},
+	}
+	showCmd.Flags().StringVarP(&commandeer.output, "output", "o", "json", "Output format [json | yaml]")
+
+	setCmd := &cobra.Command{
+		Use:   "set [container-name] [table-path] [-f input-file]",
+		Short: "Replace the table schema with a json or yaml schema from input file or stdin",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// the confirmation prompt reads from stdin as well
+			if rootCommandeer.inFile == "" && !commandeer.force {
+				return fmt.Errorf("use --force when reading the schema from stdin")
+			}
+
+			newSchema, err := commandeer.readInput()
+			if err != nil {
+				return err
+			}
+
+			schema, err := commandeer.loadOrEmpty()
+			if err != nil {
+				return err
+			}
+
+			if err := schema.CheckCompatible(newSchema); err != nil {
+				return err
+			}
+			return commandeer.write(schema, newSchema)
+		},
+	}
+
+	diffCmd := &cobra.Command{
+		Use:   "diff [container-name] [table-path] [-f input-file]",
+		Short: "Show the changes between the table schema and a json or yaml schema from input file or stdin",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			newSchema, err := commandeer.readInput()
+			if err != nil {
+				return err
+			}
+
+			schema, err := commandeer.loadOrEmpty()
+			if err != nil {
+				return err
+			}
+
+			commandeer.printDiff(schema, newSchema)
+			return schema.CheckCompatible(newSchema)
+		},
+	}
+
+	addColumnCmd := &cobra.Command{
+		Use:   "add-column [container-name] [table-path] --name name --type type [--nullable]",
+		Short: "Add a column to the table schema",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			switch commandeer.ftype {
+			case "long", "double", "string", "time", "boolean", "blob":
+			default:
+				return fmt.Errorf("Column type %s is invalid, use long | double | string | time | boolean | blob", commandeer.ftype)
+			}
+
+			schema, err := commandeer.loadOrEmpty()
+			if err != nil {
+				return err
+			}
+
+			newSchema := copySchema(schema)
+			for _, field := range schema.Fields {
+				if field.Name == commandeer.name {
+					return fmt.Errorf("column %s already exists", commandeer.name)
+				}
+			}
+			newSchema.Fields = append(newSchema.Fields,
+				utils.OldSchemaField{Name: commandeer.name, Type: commandeer.ftype, Nullable: commandeer.nullable})
+
+			return commandeer.write(schema, newSchema)
+		},
+	}
+	addColumnCmd.Flags().StringVar(&commandeer.ftype, "type", "", "Column type [long | double | string | time | boolean | blob]")
+	addColumnCmd.Flags().BoolVar(&commandeer.nullable, "nullable", false, "The column may be missing from items")
+
+	dropColumnCmd := &cobra.Command{
+		Use:   "drop-column [container-name] [table-path] --name name",
+		Short: "Remove a column from the table schema",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			schema, err := commandeer.load()
+			if err != nil {
+				return err
+			}
+
+			newSchema := copySchema(schema)
+			newSchema.Fields = []utils.OldSchemaField{}
+			for _, field := range schema.Fields {
+				if field.Name != commandeer.name {
+					newSchema.Fields = append(newSchema.Fields, field)
+				}
+			}
+			if len(newSchema.Fields) == len(schema.Fields) {
+				return fmt.Errorf("column %s was not found in the schema", commandeer.name)
+			}
+			if newSchema.Key == commandeer.name {
+				return fmt.Errorf("can't drop the key column %s", commandeer.name)
+			}
+
+			return commandeer.write(schema, newSchema)
+		},
+	}
+
+	setKeyCmd := &cobra.Command{
+		Use:   "set-key [container-name] [table-path] --key name",
+		Short: "Set the key column of the table schema",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if commandeer.key == "" {
+				return fmt.Errorf("missing key column name (--key)")
+			}
+
+			schema, err := commandeer.loadOrEmpty()
+			if err != nil {
+				return err
+			}
+
+			newSchema := copySchema(schema)
+			newSchema.Key = commandeer.key
+
+			return commandeer.write(schema, newSchema)
+		},
+	}
+	setKeyCmd.Flags().StringVar(&commandeer.key, "key", "", "Name of the key column")
+
+	for _, sub := range []*cobra.Command{addColumnCmd, dropColumnCmd} {
+		sub.Flags().StringVar(&commandeer.name, "name", "", "Column name")
+		sub.MarkFlagRequired("name")
+	}
+	for _, sub := range []*cobra.Command{setCmd, diffCmd} {
+		sub.Flags().StringVarP(&rootCommandeer.inFile, "input-file", "f", "", "Input file (json or yaml) for the schema")
+	}
+	for _, sub := range []*cobra.Command{setCmd, addColumnCmd, dropColumnCmd, setKeyCmd} {
+		sub.Flags().BoolVar(&commandeer.force, "force", false,
+			"Forceful update - don't display an update-verification prompt.")
+	}
+
+	cmd.AddCommand(showCmd, setCmd, diffCmd, addColumnCmd, dropColumnCmd, setKeyCmd)
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func (c *schemaCommandeer) tablePath() string {
+	return endWithSlash(c.rootCommandeer.dirPath)
+}
+
+func (c *schemaCommandeer) init() error {
+	if c.rootCommandeer.dirPath == "" {
+		return fmt.Errorf("missing table path")
+	}
+
+	if err := c.rootCommandeer.initialize(); err != nil {
+		return err
+	}
+
+	var err error
+	c.container, err = c.rootCommandeer.initV3io()
+	return err
+}
+
+func (c *schemaCommandeer) load() (*utils.OldV3ioSchema, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+
+	return utils.LoadSchema(c.container, c.tablePath())
+}
+
+// load the schema, a table without a schema is treated as an empty schema
+func (c *schemaCommandeer) loadOrEmpty() (*utils.OldV3ioSchema, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+
+	schema, err := utils.LoadSchema(c.container, c.tablePath())
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return utils.NewSchema("").(*utils.OldV3ioSchema), nil
+		}
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// read a json or yaml schema from the input
+func (c *schemaCommandeer) readInput() (*utils.OldV3ioSchema, error) {
+	data, err := ioutil.ReadAll(c.rootCommandeer.in)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading input file (%v)\n", err)
+	}
+
+	body, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema (%v)", err)
+	}
+
+	schema, err := utils.SchemaFromJson(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema (%v)", err)
+	}
+
+	return schema.(*utils.OldV3ioSchema), nil
+}
+
+func (c *schemaCommandeer) print(schema *utils.OldV3ioSchema) error {
+	body, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(c.output) {
+	case "json":
+	case "yaml":
+		body, err = yaml.JSONToYAML(body)
+		if err != nil {
+			return err
+		}
+	default:
+		return validateFormat(c.output, "json", "yaml")
+	}
+
+	fmt.Fprintln(c.rootCommandeer.out, strings.TrimSpace(string(body)))
+	return nil
+}
+
+func (c *schemaCommandeer) printDiff(schema, newSchema *utils.OldV3ioSchema) []string {
+	changes := schema.Diff(newSchema)
+	if len(changes) == 0 {
+		fmt.Fprintln(c.rootCommandeer.out, "No schema changes")
+	}
+	for _, change := range changes {
+		fmt.Fprintln(c.rootCommandeer.out, change)
+	}
+
+	return changes
+}
+
+// show the changes against the live schema and write the new schema once confirmed
+func (c *schemaCommandeer) write(schema, newSchema *utils.OldV3ioSchema) error {
+	if len(c.printDiff(schema, newSchema)) == 0 {
+		return nil
+	}
+
+	if !c.force {
+		confirmedByUser, err := getConfirmation(
+			fmt.Sprintf("You are about to update the schema of '%s' in container '%s'. Are you sure?",
+				c.rootCommandeer.dirPath, c.rootCommandeer.container))
+		if err != nil {
+			return err
+		}
+
+		if !confirmedByUser {
+			return fmt.Errorf("Schema update cancelled by the user.")
+		}
+	}
+
+	return newSchema.Save(c.container, c.tablePath())
+}
+
+func copySchema(schema *utils.OldV3ioSchema) *utils.OldV3ioSchema {
+	newSchema := *schema
+	newSchema.Fields = append([]utils.OldSchemaField{}, schema.Fields...)
+	return &newSchema
+}
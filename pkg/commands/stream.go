@@ -21,12 +21,24 @@ such restriction.
 package commands
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
 	"github.com/v3io/v3io-go-http"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type createStreamCommandeer struct {
@@ -77,51 +89,28 @@ func NewCmdCreatestream(rootCommandeer *RootCommandeer) *createStreamCommandeer
 	return commandeer
 }
 
-type getrecordCommandeer struct {
+type deleteStreamCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
-	seek           string
-	time           int
-	maxrec         int
-	sequence       int
-	watch          int
+	force          bool
 }
 
-func NewCmdGetrecord(rootCommandeer *RootCommandeer) *getrecordCommandeer {
+func NewCmdDeletestream(rootCommandeer *RootCommandeer) *deleteStreamCommandeer {
 
-	commandeer := &getrecordCommandeer{
+	commandeer := &deleteStreamCommandeer{
 		rootCommandeer: rootCommandeer,
 	}
 
 	cmd := &cobra.Command{
-		Use:     "getrecords [container-name] [stream-path/shard-id] [-s seek][-t time][-n seq][-m max][-w int]",
-		Short:   "Retrive one or more stream records",
-		Aliases: []string{"gr"},
+		Use:     "deletestream [container-name] [stream-path]",
+		Short:   "Delete a stream and all its shards",
+		Aliases: []string{"ds"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			root := commandeer.rootCommandeer
 			if root.dirPath == "" {
-				return fmt.Errorf("missing stream path (<stream>/<shard-id>")
-			}
-			input := v3io.SeekShardInput{Path: root.dirPath}
-
-			switch strings.ToLower(commandeer.seek) {
-			case "time":
-				input.Type = v3io.SeekShardInputTypeTime
-				input.Timestamp = commandeer.time
-			case "seq", "sequence":
-				input.Type = v3io.SeekShardInputTypeSequence
-				input.StartingSequenceNumber = commandeer.sequence
-			case "latest", "late":
-				input.Type = v3io.SeekShardInputTypeLatest
-			case "earliest":
-				input.Type = v3io.SeekShardInputTypeEarliest
-			default:
-				return fmt.Errorf(
-					"Stream seek type %s is invalid, use time | seq | latest | earliest", commandeer.seek)
-
+				return fmt.Errorf("missing stream path")
 			}
-
 			if err := root.initialize(); err != nil {
 				return err
 			}
@@ -131,56 +120,506 @@ func NewCmdGetrecord(rootCommandeer *RootCommandeer) *getrecordCommandeer {
 				return err
 			}
 
-			resp, err := container.Sync.SeekShard(&input)
+			streamPath := endWithSlash(root.dirPath)
+			shards, err := utils.ListShards(container, streamPath)
 			if err != nil {
-				return fmt.Errorf("Error in Seek operation, make sure the path include the shard id (e.g. <stream>/0) - %v", err)
+				return err
 			}
-			location := resp.Output.(*v3io.SeekShardOutput).Location
-			fmt.Fprintln(root.out, "Seek location:", location)
-
-			for {
-				resp, err := container.Sync.GetRecords(&v3io.GetRecordsInput{
-					Path:     root.dirPath,
-					Location: location,
-					Limit:    commandeer.maxrec,
-				})
 
+			if !commandeer.force {
+				confirmedByUser, err := getConfirmation(
+					fmt.Sprintf("You are about to delete the stream '%s' (%d shards) in container '%s'. Are you sure?",
+						root.dirPath, len(shards), root.container))
 				if err != nil {
-					return fmt.Errorf("Error in GetRecords operation (%v)", err)
+					return err
 				}
-				output := resp.Output.(*v3io.GetRecordsOutput)
-				for _, r := range output.Records {
-					fmt.Fprintln(root.out, "Time:", time.Unix(int64(r.ArrivalTimeSec), int64(r.ArrivalTimeNSec)),
-						"Seq:", r.SequenceNumber, "PartitionKey:", r.PartitionKey)
-					if r.ClientInfo != nil {
-						fmt.Fprintf(root.out, "ClientInfo: %s\nData:\n", string(r.ClientInfo))
-					}
-					fmt.Fprintf(root.out, "%s\n", string(r.Data))
+
+				if !confirmedByUser {
+					return fmt.Errorf("Delete cancelled by the user.")
 				}
+			}
+
+			// DeleteStream ignores shard delete errors, check which shards are left
+			deleteErr := container.Sync.DeleteStream(&v3io.DeleteStreamInput{Path: streamPath})
+			remaining, err := remainingShards(container, streamPath)
+			if err != nil {
+				return fmt.Errorf("Failed to verify the deletion of stream %s (%v)", root.dirPath, err)
+			}
 
-				location = output.NextLocation
-				if output.RecordsBehindLatest == 0 {
-					if commandeer.watch == 0 {
-						return nil
-					}
-					time.Sleep(time.Duration(commandeer.watch) * time.Second)
+			failed := []string{}
+			for _, shard := range shards {
+				if remaining[shard.ID] {
+					failed = append(failed, shard.Path)
+				} else {
+					fmt.Fprintf(root.out, "Deleted shard %d (%s)\n", shard.ID, shard.Path)
 				}
 			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("Failed to delete %d shards: %s", len(failed), strings.Join(failed, ", "))
+			}
+			if deleteErr != nil {
+				return fmt.Errorf("Failed to delete stream %s (%v)", root.dirPath, deleteErr)
+			}
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVarP(&commandeer.force, "force", "f", false,
+		"Forceful deletion - don't display a delete-verification prompt.")
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+// return the ids of the shards left in the stream directory, a missing or empty directory has no shards
+func remainingShards(container *v3io.Container, streamPath string) (map[int]bool, error) {
+	remaining := map[int]bool{}
+	resp, err := container.Sync.ListBucket(&v3io.ListBucketInput{Path: streamPath})
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return remaining, nil
+		}
+		return nil, err
+	}
+	defer resp.Release()
+
+	for _, content := range resp.Output.(*v3io.ListBucketOutput).Contents {
+		if id, err := strconv.Atoi(path.Base(content.Key)); err == nil {
+			remaining[id] = true
+		}
+	}
+
+	return remaining, nil
+}
+
+type describeStreamCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	output         string
+}
+
+func NewCmdDescribestream(rootCommandeer *RootCommandeer) *describeStreamCommandeer {
+
+	commandeer := &describeStreamCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "describestream [container-name] [stream-path] [-o table|json]",
+		Short:   "Show the stream shards, retention and the earliest/latest record of every shard",
+		Aliases: []string{"dsc"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.describe()
+		},
+	}
+
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", "table", "Output format [table | json]")
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func (c *describeStreamCommandeer) describe() error {
+
+	if err := validateFormat(c.output, "table", "json"); err != nil {
+		return err
+	}
+
+	root := c.rootCommandeer
+	if root.dirPath == "" {
+		return fmt.Errorf("missing stream path")
+	}
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	streamPath := endWithSlash(root.dirPath)
+	shards, err := utils.ListShards(container, streamPath)
+	if err != nil {
+		return err
+	}
+
+	config, err := describeStream(root, streamPath)
+	if err != nil {
+		root.logger.WarnWith("Failed to get the stream configuration", "path", streamPath, "err", err)
+		config = &streamConfig{ShardCount: len(shards)}
+	}
+
+	infos := make([]*utils.ShardInfo, len(shards))
+	for i, shard := range shards {
+		if infos[i], err = utils.DescribeShard(container, shard); err != nil {
+			return err
+		}
+	}
+
+	if strings.ToLower(c.output) == "json" {
+		body, err := json.MarshalIndent(map[string]interface{}{
+			"path":            root.dirPath,
+			"shard_count":     config.ShardCount,
+			"retention_hours": config.RetentionPeriodHours,
+			"shards":          infos}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(root.out, string(body))
+		return nil
+	}
+
+	fmt.Fprintf(root.out, "Stream: %s\nShards: %d\n", root.dirPath, config.ShardCount)
+	if config.RetentionPeriodHours > 0 {
+		fmt.Fprintf(root.out, "Retention: %d hours\n", config.RetentionPeriodHours)
+	}
+
+	columns := []string{"shard", "records", "earliest_seq", "latest_seq", "earliest_time", "latest_time", "size"}
+	rows := make([][]interface{}, len(infos))
+	for i, info := range infos {
+		rows[i] = []interface{}{info.ID, info.Records, nil, nil, nil, nil, info.Size}
+		if info.Records > 0 {
+			rows[i][2], rows[i][3] = info.EarliestSequence, info.LatestSequence
+			rows[i][4], rows[i][5] = info.EarliestTime.Format(time.RFC3339Nano), info.LatestTime.Format(time.RFC3339Nano)
+		}
+	}
+
+	return writeRows(root.out, c.output, columns, rows)
+}
+
+type streamConfig struct {
+	ShardCount           int
+	RetentionPeriodHours int
+}
+
+// the SDK has no DescribeStream, call the web API directly (as done in listAll)
+func describeStream(rc *RootCommandeer, streamPath string) (*streamConfig, error) {
+
+	endpoint := rc.v3iocfg.WebApiEndpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	url := fmt.Sprintf("%s/%s/%s", endpoint, rc.container, strings.TrimPrefix(streamPath, "/"))
+	req, err := http.NewRequest("PUT", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-v3io-function", "DescribeStream")
+	if rc.v3iocfg.SessionKey != "" {
+		req.Header.Set("X-v3io-session-key", rc.v3iocfg.SessionKey)
+	} else {
+		req.SetBasicAuth(rc.v3iocfg.Username, rc.v3iocfg.Password)
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DescribeStream failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	config := streamConfig{}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+type getrecordCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	seek           string
+	time           int
+	maxrec         int
+	sequence       int
+	watch          int
+	unordered      bool
+	from           string
+	to             string
+	output         recordOutput
+}
+
+const GetRecordsExamples string = `   v3ctl getrecords datalake mystream              # read all shards, merged by arrival time
+   v3ctl getrecords datalake mystream/0 -k latest -w   # watch shard 0 for new records
+   v3ctl getrecords datalake mystream -o ndjson        # one json object per record
+   v3ctl getrecords datalake mystream --from 2018-06-01T22:00:00Z --to 2018-06-02T02:00:00Z
+   v3ctl getrecords datalake mystream --from -15m      # records of the last 15 minutes
+   v3ctl getrecords datalake mystream -o raw --delimiter '\x00'   # payloads only`
+
+func NewCmdGetrecord(rootCommandeer *RootCommandeer) *getrecordCommandeer {
+
+	commandeer := &getrecordCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "getrecords [container-name] [stream-path[/shard-id]] [-k seek][-t time][-n seq][-m max][-w int]",
+		Short:   "Retrive one or more stream records",
+		Example: GetRecordsExamples,
+		Aliases: []string{"gr"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.getrecords()
+		},
+	}
+
 	cmd.Flags().StringVarP(&commandeer.seek, "seek", "k", "EARLIEST", "Relative stream location [EARLIEST | LATEST | SEQUENCE | TIME]")
-	cmd.Flags().IntVarP(&commandeer.time, "time", "t", 0, "Starting time - for TIME seek")
+	cmd.Flags().IntVarP(&commandeer.time, "time", "t", 0, "Starting time (epoch seconds) - for TIME seek, see also --from")
+	cmd.Flags().StringVar(&commandeer.from, "from", "",
+		"Read records from this time (TIME seek), RFC3339, epoch or relative to now (e.g. -15m, -2h, -1d)")
+	cmd.Flags().StringVar(&commandeer.to, "to", "",
+		"Stop reading once records arrived after this time, RFC3339, epoch or relative to now")
 	cmd.Flags().IntVarP(&commandeer.sequence, "sequence", "n", 0, "Starting sequence - for SEQUENCE seek")
 	cmd.Flags().IntVarP(&commandeer.maxrec, "max-rec", "m", 50, "Max Records/Items to get per call")
 	cmd.Flags().IntVarP(&commandeer.watch, "watch", "w", 0, "Watch object, read every N secounds (blocking)")
 	cmd.Flags().Lookup("watch").NoOptDefVal = "2"
+	cmd.Flags().BoolVar(&commandeer.unordered, "unordered", false,
+		"Write records as they are read from the shards rather than merged by arrival time")
+	addRecordOutputFlags(cmd, &commandeer.output)
 
 	commandeer.cmd = cmd
 	return commandeer
 }
 
+func (c *getrecordCommandeer) getrecords() error {
+
+	root := c.rootCommandeer
+	if root.dirPath == "" {
+		return fmt.Errorf("missing stream path (<stream> or <stream>/<shard-id>)")
+	}
+	writer, err := newRecordWriter(root.out, c.output)
+	if err != nil {
+		return err
+	}
+	input := v3io.SeekShardInput{}
+
+	switch strings.ToLower(c.seek) {
+	case "time":
+		input.Type = v3io.SeekShardInputTypeTime
+		input.Timestamp = c.time
+	case "seq", "sequence":
+		input.Type = v3io.SeekShardInputTypeSequence
+		input.StartingSequenceNumber = c.sequence
+	case "latest", "late":
+		input.Type = v3io.SeekShardInputTypeLatest
+	case "earliest":
+		input.Type = v3io.SeekShardInputTypeEarliest
+	default:
+		return fmt.Errorf(
+			"Stream seek type %s is invalid, use time | seq | latest | earliest", c.seek)
+
+	}
+
+	now := time.Now()
+	config := utils.StreamReaderConfig{
+		Limit: c.maxrec, Watch: time.Duration(c.watch) * time.Second, Unordered: c.unordered}
+	if c.from != "" {
+		if config.From, err = utils.ParseTime(c.from, now); err != nil {
+			return err
+		}
+		input.Type = v3io.SeekShardInputTypeTime
+		input.Timestamp = int(config.From.Unix())
+	}
+	if c.to != "" {
+		if config.To, err = utils.ParseTime(c.to, now); err != nil {
+			return err
+		}
+		if !config.From.IsZero() && config.To.Before(config.From) {
+			return fmt.Errorf("--to must not be before --from")
+		}
+	}
+
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	shards, err := streamShards(container, root.dirPath)
+	if err != nil {
+		return err
+	}
+
+	reader := utils.NewStreamReader(container, shards,
+		func(shard utils.StreamShard) v3io.SeekShardInput { return input }, config)
+	defer reader.Close()
+	defer writer.close()
+
+	for reader.Next() {
+		if err := writer.write(reader.Record()); err != nil {
+			return err
+		}
+	}
+
+	return reader.Err()
+}
+
+type recordOutput struct {
+	format     string
+	dataFormat string
+	delimiter  string
+}
+
+func addRecordOutputFlags(cmd *cobra.Command, output *recordOutput) {
+	cmd.Flags().StringVarP(&output.format, "output", "o", "text", "Output format [text | ndjson | json | raw]")
+	cmd.Flags().StringVar(&output.dataFormat, "data-format", "auto",
+		"Record data in ndjson/json output [auto | string | base64 | json], auto embeds json payloads and\nuses base64 for binary payloads")
+	cmd.Flags().StringVar(&output.delimiter, "delimiter", "\\n", "Delimiter written after every payload in raw output, may use Go escapes such as \\t or \\x00")
+}
+
+// recordWriter writes stream records in the selected output format
+type recordWriter struct {
+	out        io.Writer
+	format     string
+	dataFormat string
+	delimiter  []byte
+	count      int
+}
+
+func newRecordWriter(out io.Writer, output recordOutput) (*recordWriter, error) {
+	writer := recordWriter{out: out, format: strings.ToLower(output.format), dataFormat: strings.ToLower(output.dataFormat)}
+	if err := validateFormat(writer.format, "text", "ndjson", "json", "raw"); err != nil {
+		return nil, err
+	}
+	switch writer.dataFormat {
+	case "auto", "string", "base64", "json":
+	default:
+		return nil, fmt.Errorf("Data format %s is invalid, use auto | string | base64 | json", output.dataFormat)
+	}
+
+	// the delimiter may use Go string escapes such as \n, \t or \x00 (a NUL is \x00 or \000, not \0)
+	delimiter, err := strconv.Unquote(`"` + strings.Replace(output.delimiter, `"`, `\"`, -1) + `"`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delimiter %s (%v)", output.delimiter, err)
+	}
+	writer.delimiter = []byte(delimiter)
+
+	return &writer, nil
+}
+
+func (w *recordWriter) write(r *utils.ShardRecord) error {
+	defer func() { w.count++ }()
+
+	switch w.format {
+	case "raw":
+		if _, err := w.out.Write(r.Data); err != nil {
+			return err
+		}
+		_, err := w.out.Write(w.delimiter)
+		return err
+	case "ndjson", "json":
+		body, err := w.marshal(r)
+		if err != nil {
+			return err
+		}
+		if w.format == "ndjson" {
+			_, err = fmt.Fprintf(w.out, "%s\n", body)
+		} else if w.count == 0 {
+			_, err = fmt.Fprintf(w.out, "[\n%s", body)
+		} else {
+			_, err = fmt.Fprintf(w.out, ",\n%s", body)
+		}
+		return err
+	}
+
+	fmt.Fprintln(w.out, "Shard:", r.Shard, "Time:", utils.RecordTime(&r.GetRecordsResult),
+		"Seq:", r.SequenceNumber, "PartitionKey:", r.PartitionKey)
+	if r.ClientInfo != nil {
+		fmt.Fprintf(w.out, "ClientInfo: %s\nData:\n", string(r.ClientInfo))
+	}
+	_, err := fmt.Fprintf(w.out, "%s\n", string(r.Data))
+	return err
+}
+
+func (w *recordWriter) marshal(r *utils.ShardRecord) ([]byte, error) {
+	columns := []string{"shard", "sequence", "arrival_time", "partition_key"}
+	values := []interface{}{r.Shard, r.SequenceNumber, utils.RecordTime(&r.GetRecordsResult).UTC().Format(time.RFC3339Nano), r.PartitionKey}
+	if r.ClientInfo != nil {
+		columns = append(columns, "client_info")
+		values = append(values, string(r.ClientInfo))
+	}
+
+	dataFormat := w.dataFormat
+	if dataFormat == "auto" {
+		switch {
+		case json.Valid(r.Data) && len(bytes.TrimSpace(r.Data)) > 0:
+			dataFormat = "json"
+		case utf8.Valid(r.Data):
+			dataFormat = "string"
+		default:
+			dataFormat = "base64"
+		}
+	}
+
+	switch dataFormat {
+	case "json":
+		if !json.Valid(r.Data) {
+			return nil, fmt.Errorf("record %d in shard %d is not json", r.SequenceNumber, r.Shard)
+		}
+		columns = append(columns, "data")
+		values = append(values, json.RawMessage(r.Data))
+	case "string":
+		columns = append(columns, "data")
+		values = append(values, string(r.Data))
+	case "base64":
+		columns = append(columns, "data_base64")
+		values = append(values, base64.StdEncoding.EncodeToString(r.Data))
+	}
+
+	return marshalOrdered(columns, values)
+}
+
+// end the output, closes the json array
+func (w *recordWriter) close() {
+	if w.format == "json" {
+		if w.count == 0 {
+			fmt.Fprintf(w.out, "[")
+		}
+		fmt.Fprintf(w.out, "\n]\n")
+	}
+}
+
+// return all the shards of a stream path, or the shard of a <stream>/<shard-id> path. The path is first
+// listed as a stream so streams with a numeric name are read whole
+func streamShards(container *v3io.Container, streamPath string) ([]utils.StreamShard, error) {
+	streamPath = strings.TrimSuffix(streamPath, "/")
+	shards, err := utils.ListShards(container, endWithSlash(streamPath))
+	if err == nil {
+		return shards, nil
+	}
+
+	id, convErr := strconv.Atoi(path.Base(streamPath))
+	if convErr != nil {
+		return nil, err
+	}
+	parentShards, parentErr := utils.ListShards(container, endWithSlash(path.Dir(streamPath)))
+	if parentErr != nil {
+		return nil, err
+	}
+	for _, shard := range parentShards {
+		if shard.ID == id {
+			return []utils.StreamShard{shard}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("stream %s has no shard %d", path.Dir(streamPath), id)
+}
+
 type putrecordCommandeer struct {
 	cmd            *cobra.Command
 	rootCommandeer *RootCommandeer
@@ -234,3 +673,233 @@ func NewCmdPutrecord(rootCommandeer *RootCommandeer) *putrecordCommandeer {
 	commandeer.cmd = cmd
 	return commandeer
 }
+
+type putrecordsCommandeer struct {
+	cmd               *cobra.Command
+	rootCommandeer    *RootCommandeer
+	ndjson            bool
+	partitionKey      string
+	partitionKeyField string
+	partitionKeyRegex string
+	shardID           int
+	clientInfo        string
+	batchSize         int
+	retries           int
+	retryDelay        time.Duration
+}
+
+const PutRecordsExamples string = `   cat events.ndjson | v3ctl putrecords datalake mystream --ndjson --partition-key-field user.id
+   v3ctl putrecords datalake mystream -f app.log --partition-key-regex 'host=(\S+)'
+   v3ctl putrecords datalake mystream -f lines.txt --shard-id 2 -b 500`
+
+func NewCmdPutrecords(rootCommandeer *RootCommandeer) *putrecordsCommandeer {
+
+	commandeer := &putrecordsCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "putrecords [container-name] [stream-path]",
+		Short:   "Upload a stream record per input line (or NDJSON object) from input file or stdin",
+		Example: PutRecordsExamples,
+		Aliases: []string{"prs"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.putrecords()
+		},
+	}
+	cmd.Flags().StringVarP(&rootCommandeer.inFile, "input-file", "f", "", "Input file for the different put* commands")
+	cmd.Flags().BoolVar(&commandeer.ndjson, "ndjson", false, "Every input line must be a json object")
+	cmd.Flags().StringVarP(&commandeer.partitionKey, "partition-key", "k", "", "Partition key (used to determine shard) for all records")
+	cmd.Flags().StringVar(&commandeer.partitionKeyField, "partition-key-field", "",
+		"Take the partition key from this json field (a.b for nested fields), implies --ndjson")
+	cmd.Flags().StringVar(&commandeer.partitionKeyRegex, "partition-key-regex", "",
+		"Take the partition key from the first submatch (or the match) of this regular expression in the line")
+	cmd.Flags().IntVar(&commandeer.shardID, "shard-id", -1, "Put all records in this shard")
+	cmd.Flags().StringVarP(&commandeer.clientInfo, "client-info", "c", "", "ClientInfo, extra metadata for the message")
+	cmd.Flags().IntVarP(&commandeer.batchSize, "batch-size", "b", 100, "Number of records per PutRecords call")
+	cmd.Flags().IntVar(&commandeer.retries, "retries", 3,
+		"Number of times to retry failed records, a retried batch may be written twice (at-least-once)")
+	cmd.Flags().DurationVar(&commandeer.retryDelay, "retry-delay", 200*time.Millisecond,
+		"Delay before the first retry, doubled on every retry")
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+type shardSummary struct {
+	records  int
+	firstSeq int
+	lastSeq  int
+}
+
+func (c *putrecordsCommandeer) putrecords() error {
+
+	keySources := 0
+	for _, source := range []string{c.partitionKey, c.partitionKeyField, c.partitionKeyRegex} {
+		if source != "" {
+			keySources++
+		}
+	}
+	if keySources > 1 {
+		return fmt.Errorf("use only one of --partition-key, --partition-key-field and --partition-key-regex")
+	}
+
+	var keyRegex *regexp.Regexp
+	if c.partitionKeyRegex != "" {
+		var err error
+		if keyRegex, err = regexp.Compile(c.partitionKeyRegex); err != nil {
+			return fmt.Errorf("invalid partition key regex (%v)", err)
+		}
+	}
+	if c.batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+
+	root := c.rootCommandeer
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	streamPath := endWithSlash(root.dirPath)
+	summary := map[int]*shardSummary{}
+	total, failed := 0, 0
+	failures := []string{}
+
+	flush := func(batch []*v3io.StreamRecord, lines []int) error {
+		results, err := utils.PutRecordsWithRetry(container, streamPath, batch, c.retries, c.retryDelay, false)
+		if err != nil {
+			return fmt.Errorf("Error in PutRecords operation (%v)", err)
+		}
+
+		for i, result := range results {
+			total++
+			if result.ErrorCode != 0 {
+				failed++
+				failures = append(failures, fmt.Sprintf("line %d: %s (%d)", lines[i], result.ErrorMessage, result.ErrorCode))
+				continue
+			}
+			shard, ok := summary[result.ShardID]
+			if !ok {
+				shard = &shardSummary{firstSeq: result.SequenceNumber}
+				summary[result.ShardID] = shard
+			}
+			shard.records++
+			if result.SequenceNumber < shard.firstSeq {
+				shard.firstSeq = result.SequenceNumber
+			}
+			if result.SequenceNumber > shard.lastSeq {
+				shard.lastSeq = result.SequenceNumber
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(root.in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	batch := []*v3io.StreamRecord{}
+	lines := []int{}
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := append([]byte{}, bytes.TrimRight(scanner.Bytes(), "\r")...)
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		record := v3io.StreamRecord{Data: line, PartitionKey: c.partitionKey}
+		if c.clientInfo != "" {
+			record.ClientInfo = []byte(c.clientInfo)
+		}
+		if c.shardID >= 0 {
+			shardID := c.shardID
+			record.ShardID = &shardID
+		}
+
+		if c.ndjson || c.partitionKeyField != "" {
+			fields := map[string]interface{}{}
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+			if err := decoder.Decode(&fields); err != nil {
+				return fmt.Errorf("line %d is not a json object (%v)", lineNum, err)
+			}
+			if c.partitionKeyField != "" {
+				record.PartitionKey = jsonField(fields, c.partitionKeyField)
+			}
+		}
+		if keyRegex != nil {
+			if match := keyRegex.FindSubmatch(line); match != nil {
+				record.PartitionKey = string(match[len(match)-1])
+			}
+		}
+
+		// the SDK doesn't escape the partition key
+		if strings.ContainsAny(record.PartitionKey, "\"\\") {
+			return fmt.Errorf("line %d: partition key %s must not contain quotes or backslashes", lineNum, record.PartitionKey)
+		}
+
+		batch = append(batch, &record)
+		lines = append(lines, lineNum)
+		if len(batch) >= c.batchSize {
+			if err := flush(batch, lines); err != nil {
+				return err
+			}
+			batch, lines = []*v3io.StreamRecord{}, []int{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading input file (%v)\n", err)
+	}
+	if len(batch) > 0 {
+		if err := flush(batch, lines); err != nil {
+			return err
+		}
+	}
+
+	shards := make([]int, 0, len(summary))
+	for shard := range summary {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+
+	rows := make([][]interface{}, len(shards))
+	for i, shard := range shards {
+		rows[i] = []interface{}{shard, summary[shard].records, summary[shard].firstSeq, summary[shard].lastSeq}
+	}
+	if err := writeRows(root.out, "table", []string{"shard", "records", "first_seq", "last_seq"}, rows); err != nil {
+		return err
+	}
+	fmt.Fprintf(root.out, "Total: %d, Failed: %d\n", total, failed)
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to put %d records:\n%s", failed, strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// return a (dot separated) json field as a string, or an empty string when missing
+func jsonField(fields map[string]interface{}, name string) string {
+	var val interface{} = fields
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		if val, ok = obj[part]; !ok {
+			return ""
+		}
+	}
+
+	switch val.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return ""
+	}
+	return fmt.Sprint(val)
+}
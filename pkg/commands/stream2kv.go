@@ -0,0 +1,174 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"net/url"
+	"os"
+)
+
+const Stream2KVExamples string = `   v3ctl stream2kv datalake events users --key user_id
+   v3ctl stream2kv datalake events users --key user_id --mode update -t joined=time -w`
+
+type stream2kvCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	consumer       consumerFlags
+	key            string
+	mode           string
+	types          []string
+	skipErrors     bool
+}
+
+func NewCmdStream2KV(rootCommandeer *RootCommandeer) *stream2kvCommandeer {
+
+	commandeer := &stream2kvCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "stream2kv [container-name] [stream-path] [table-path] --key field",
+		Short:   "Write json stream records to a table as items, keyed by a record field",
+		Example: Stream2KVExamples,
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.stream2kv(args[2])
+		},
+	}
+
+	cmd.Flags().StringVar(&commandeer.key, "key", "", "Record field (a string) to use as the item key")
+	cmd.MarkFlagRequired("key")
+	cmd.Flags().StringVar(&commandeer.mode, "mode", "put",
+		"Write mode [put | update], put replaces the item and update merges the record fields into it")
+	cmd.Flags().StringSliceVarP(&commandeer.types, "types", "t", []string{},
+		"Attribute type hints seperated by ',', see putitem")
+	cmd.Flags().BoolVar(&commandeer.skipErrors, "skip-errors", false,
+		"Skip (and report) records that can't be converted to items rather than stopping")
+	addConsumerFlags(cmd, &commandeer.consumer, "stream2kv")
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func (c *stream2kvCommandeer) stream2kv(table string) error {
+
+	switch c.mode {
+	case "put", "update":
+	default:
+		return fmt.Errorf("Write mode %s is invalid, use put | update", c.mode)
+	}
+
+	types, err := utils.ParseTypeHints(c.types)
+	if err != nil {
+		return err
+	}
+
+	root := c.rootCommandeer
+	config, err := c.consumer.config(root.dirPath)
+	if err != nil {
+		return err
+	}
+
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	shards, err := utils.ListShards(container, endWithSlash(root.dirPath))
+	if err != nil {
+		return err
+	}
+
+	consumer, err := utils.NewConsumer(container, shards, *config)
+	if err != nil {
+		return err
+	}
+	defer stopOnInterrupt(consumer)()
+
+	written, skipped := 0, 0
+	tablePath := endWithSlash(table)
+	for consumer.Next() {
+		record := consumer.Record()
+		key, attributes, err := c.item(record, types)
+		if err != nil && !c.skipErrors {
+			consumer.Close()
+			return err
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipped %v\n", err)
+			skipped++
+		} else {
+			itemPath := tablePath + url.QueryEscape(key)
+			if c.mode == "update" {
+				err = container.Sync.UpdateItem(&v3io.UpdateItemInput{Path: itemPath, Attributes: attributes})
+			} else {
+				err = container.Sync.PutItem(&v3io.PutItemInput{Path: itemPath, Attributes: attributes})
+			}
+			// write failures are not skipped, the record is read again on the next run
+			if err != nil {
+				consumer.Close()
+				return fmt.Errorf("Failed to write item %s (%v)", key, err)
+			}
+			written++
+		}
+
+		if err := consumer.Done(record); err != nil {
+			consumer.Close()
+			return err
+		}
+	}
+
+	fmt.Fprintf(root.out, "Written: %d, Skipped: %d\n", written, skipped)
+	if err := consumer.Close(); err != nil {
+		return err
+	}
+	return consumer.Err()
+}
+
+// convert a json record to the item key and attributes
+func (c *stream2kvCommandeer) item(record *utils.ShardRecord, types map[string]string) (string, map[string]interface{}, error) {
+	attributes, err := utils.DecodeItemJson(record.Data, types)
+	if err != nil {
+		return "", nil, fmt.Errorf("record %d in shard %d: not a flat json object (%v)", record.SequenceNumber, record.Shard, err)
+	}
+
+	keyValue, ok := attributes[c.key]
+	if !ok {
+		return "", nil, fmt.Errorf("record %d in shard %d: missing key field %s", record.SequenceNumber, record.Shard, c.key)
+	}
+	key, ok := keyValue.(string)
+	if !ok || key == "" {
+		return "", nil, fmt.Errorf("record %d in shard %d: invalid key %v, the key field must be a non empty string",
+			record.SequenceNumber, record.Shard, keyValue)
+	}
+
+	return key, attributes, nil
+}
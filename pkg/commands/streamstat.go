@@ -0,0 +1,307 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/v3io/v3cli/pkg/utils"
+	"github.com/v3io/v3io-go-http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const StreamStatExamples string = `   v3ctl streamstat datalake events                    # refreshing table, every 5 seconds
+   v3ctl streamstat datalake events -i 1m -o ndjson >> events-stats.ndjson
+   v3ctl streamstat datalake events --positions 0=1520,1=1498 -n 1   # lag of a consumer`
+
+type streamStatCommandeer struct {
+	cmd            *cobra.Command
+	rootCommandeer *RootCommandeer
+	interval       time.Duration
+	samples        int
+	output         string
+	positions      []string
+}
+
+type shardStat struct {
+	Shard         int        `json:"shard"`
+	LatestSeq     int        `json:"latest_sequence"`
+	LatestTime    *time.Time `json:"latest_time,omitempty"`
+	RecordsPerSec *float64   `json:"records_per_sec,omitempty"`
+	BytesPerSec   *float64   `json:"bytes_per_sec,omitempty"`
+	LagRecords    *int       `json:"lag_records,omitempty"`
+	LagMSec       *int64     `json:"lag_ms,omitempty"`
+
+	size int
+}
+
+type streamSample struct {
+	Time          time.Time    `json:"time"`
+	RecordsPerSec *float64     `json:"records_per_sec,omitempty"`
+	BytesPerSec   *float64     `json:"bytes_per_sec,omitempty"`
+	RateSkew      *float64     `json:"rate_skew,omitempty"`
+	TimeSkewMSec  int64        `json:"time_skew_ms"`
+	Shards        []*shardStat `json:"shards"`
+}
+
+func NewCmdStreamStat(rootCommandeer *RootCommandeer) *streamStatCommandeer {
+
+	commandeer := &streamStatCommandeer{
+		rootCommandeer: rootCommandeer,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "streamstat [container-name] [stream-path] [-i interval] [-o table|ndjson]",
+		Short:   "Monitor the ingest rate, shard skew and consumer lag of a stream",
+		Example: StreamStatExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return commandeer.streamstat()
+		},
+	}
+
+	cmd.Flags().DurationVarP(&commandeer.interval, "interval", "i", 5*time.Second, "Sampling interval")
+	cmd.Flags().IntVarP(&commandeer.samples, "samples", "n", 0, "Number of samples to take (0 to run until stopped)")
+	cmd.Flags().StringVarP(&commandeer.output, "output", "o", "table", "Output format [table | ndjson]")
+	cmd.Flags().StringSliceVar(&commandeer.positions, "positions", []string{},
+		"Consumer position per shard to report the lag of, seperated by ',', e.g. 0=1520,1=1498")
+
+	commandeer.cmd = cmd
+	return commandeer
+}
+
+func (c *streamStatCommandeer) streamstat() error {
+
+	c.output = strings.ToLower(c.output)
+	if err := validateFormat(c.output, "table", "ndjson"); err != nil {
+		return err
+	}
+	if c.interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	positions := map[int]int{}
+	for _, position := range c.positions {
+		parts := strings.SplitN(position, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid position '%s', expected shard=sequence", position)
+		}
+		shard, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return fmt.Errorf("invalid shard id in position '%s'", position)
+		}
+		seq, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("invalid sequence number in position '%s'", position)
+		}
+		positions[shard] = seq
+	}
+
+	root := c.rootCommandeer
+	if root.dirPath == "" {
+		return fmt.Errorf("missing stream path")
+	}
+	if err := root.initialize(); err != nil {
+		return err
+	}
+
+	container, err := root.initV3io()
+	if err != nil {
+		return err
+	}
+
+	var previous *streamSample
+	for count := 0; c.samples == 0 || count < c.samples; count++ {
+		if count > 0 {
+			time.Sleep(c.interval)
+		}
+
+		sample, err := c.sample(container, positions, previous)
+		if err != nil {
+			return err
+		}
+		if err := c.write(sample, count); err != nil {
+			return err
+		}
+		previous = sample
+	}
+
+	return nil
+}
+
+// sample the latest record of every shard, rates are computed against the previous sample
+func (c *streamStatCommandeer) sample(container *v3io.Container, positions map[int]int, previous *streamSample) (*streamSample, error) {
+
+	shards, err := utils.ListShards(container, endWithSlash(c.rootCommandeer.dirPath))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[int]bool{}
+	for _, shard := range shards {
+		ids[shard.ID] = true
+	}
+	for id := range positions {
+		if !ids[id] {
+			return nil, fmt.Errorf("invalid position for shard %d, the stream has no such shard", id)
+		}
+	}
+
+	sample := streamSample{Time: time.Now(), Shards: make([]*shardStat, len(shards))}
+	errors := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard utils.StreamShard) {
+			defer wg.Done()
+			sample.Shards[i], errors[i] = shardSample(container, shard, positions)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	for _, err := range errors {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var minTime, maxTime time.Time
+	for _, stat := range sample.Shards {
+		if stat.LatestTime == nil {
+			continue
+		}
+		if minTime.IsZero() || stat.LatestTime.Before(minTime) {
+			minTime = *stat.LatestTime
+		}
+		if stat.LatestTime.After(maxTime) {
+			maxTime = *stat.LatestTime
+		}
+	}
+	sample.TimeSkewMSec = int64(maxTime.Sub(minTime) / time.Millisecond)
+
+	if previous == nil {
+		return &sample, nil
+	}
+
+	prevStats := map[int]*shardStat{}
+	for _, stat := range previous.Shards {
+		prevStats[stat.Shard] = stat
+	}
+
+	elapsed := sample.Time.Sub(previous.Time).Seconds()
+	totalRecords, totalBytes, maxRate, rated := 0.0, 0.0, 0.0, 0
+	for _, stat := range sample.Shards {
+		prev, ok := prevStats[stat.Shard]
+		if !ok {
+			continue
+		}
+		records := float64(stat.LatestSeq-prev.LatestSeq) / elapsed
+		// the shard size shrinks when old records expire
+		bytes := float64(stat.size-prev.size) / elapsed
+		if bytes < 0 {
+			bytes = 0
+		}
+		stat.RecordsPerSec, stat.BytesPerSec = &records, &bytes
+
+		rated++
+		totalRecords += records
+		totalBytes += bytes
+		if records > maxRate {
+			maxRate = records
+		}
+	}
+	sample.RecordsPerSec, sample.BytesPerSec = &totalRecords, &totalBytes
+
+	// the busiest shard rate relative to the average rate of the shards in both samples, 1 means an even spread
+	if totalRecords > 0 {
+		skew := maxRate / (totalRecords / float64(rated))
+		sample.RateSkew = &skew
+	}
+
+	return &sample, nil
+}
+
+func shardSample(container *v3io.Container, shard utils.StreamShard, positions map[int]int) (*shardStat, error) {
+	info, err := utils.DescribeShard(container, shard)
+	if err != nil {
+		return nil, err
+	}
+
+	stat := shardStat{Shard: shard.ID, LatestSeq: info.LatestSequence, LatestTime: info.LatestTime, size: shard.Size}
+	if position, ok := positions[shard.ID]; ok {
+		lagRecords, lagTime, err := utils.ShardLag(container, shard, position)
+		if err != nil {
+			return nil, err
+		}
+		lagMSec := int64(lagTime / time.Millisecond)
+		stat.LagRecords, stat.LagMSec = &lagRecords, &lagMSec
+	}
+
+	return &stat, nil
+}
+
+func (c *streamStatCommandeer) write(sample *streamSample, count int) error {
+	out := c.rootCommandeer.out
+	if c.output == "ndjson" {
+		body, err := json.Marshal(sample)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", body)
+		return err
+	}
+
+	// refresh the table in place
+	if c.samples != 1 {
+		fmt.Fprint(out, "\033[H\033[2J")
+	}
+	fmt.Fprintf(out, "Stream: %s  %s  (sample %d, every %s)\n\n",
+		c.rootCommandeer.dirPath, sample.Time.Format(time.RFC3339), count+1, c.interval)
+
+	columns := []string{"shard", "latest_seq", "latest_time", "records/s", "bytes/s", "lag_records", "lag"}
+	rows := make([][]interface{}, len(sample.Shards))
+	for i, stat := range sample.Shards {
+		rows[i] = []interface{}{stat.Shard, stat.LatestSeq, nil, floatOrNil(stat.RecordsPerSec), floatOrNil(stat.BytesPerSec), nil, nil}
+		if stat.LatestTime != nil {
+			rows[i][2] = stat.LatestTime.Format(time.RFC3339Nano)
+		}
+		if stat.LagRecords != nil {
+			rows[i][5], rows[i][6] = *stat.LagRecords, time.Duration(*stat.LagMSec)*time.Millisecond
+		}
+	}
+	if err := writeRows(out, "table", columns, rows); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nLatest time skew: %s", time.Duration(sample.TimeSkewMSec)*time.Millisecond)
+	if sample.RecordsPerSec != nil {
+		fmt.Fprintf(out, "  Total: %.1f records/s, %.1f bytes/s", *sample.RecordsPerSec, *sample.BytesPerSec)
+	}
+	if sample.RateSkew != nil {
+		fmt.Fprintf(out, "  Rate skew (max/avg): %.2f", *sample.RateSkew)
+	}
+	fmt.Fprintln(out)
+	return nil
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Aggregate struct {
+	Function  string
+	Attribute string
+}
+
+// ParseAggregates parses aggregate specs of the form func(attr), e.g. sum(price) or count(*)
+func ParseAggregates(specs []string) ([]Aggregate, error) {
+	aggregates := []Aggregate{}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		open := strings.Index(spec, "(")
+		if open <= 0 || !strings.HasSuffix(spec, ")") {
+			return nil, fmt.Errorf("invalid aggregate '%s', expected func(attr)", spec)
+		}
+
+		aggr := Aggregate{
+			Function:  strings.ToLower(strings.TrimSpace(spec[:open])),
+			Attribute: strings.TrimSpace(spec[open+1 : len(spec)-1])}
+
+		switch aggr.Function {
+		case "count", "sum", "avg", "min", "max":
+		default:
+			return nil, fmt.Errorf("unsupported aggregate function '%s', use count | sum | avg | min | max", aggr.Function)
+		}
+		if aggr.Attribute == "" || (aggr.Attribute == "*" && aggr.Function != "count") {
+			return nil, fmt.Errorf("invalid attribute in aggregate '%s'", spec)
+		}
+
+		aggregates = append(aggregates, aggr)
+	}
+
+	return aggregates, nil
+}
+
+func (a Aggregate) String() string {
+	return fmt.Sprintf("%s(%s)", a.Function, a.Attribute)
+}
+
+type aggrState struct {
+	count int
+	sum   float64
+	min   interface{}
+	max   interface{}
+}
+
+type aggrGroup struct {
+	values []interface{}
+	states []aggrState
+}
+
+// Aggregator computes aggregates over a stream of items, grouped by the values of the group-by attributes
+type Aggregator struct {
+	groupBy    []string
+	aggregates []Aggregate
+	groups     map[string]*aggrGroup
+}
+
+func NewAggregator(groupBy []string, aggregates []Aggregate) *Aggregator {
+	return &Aggregator{groupBy: groupBy, aggregates: aggregates, groups: map[string]*aggrGroup{}}
+}
+
+// Attributes returns the item attributes required for the aggregation
+func (ag *Aggregator) Attributes() []string {
+	attrs := []string{}
+	seen := map[string]bool{}
+	add := func(attr string) {
+		if attr != "*" && !seen[attr] {
+			seen[attr] = true
+			attrs = append(attrs, attr)
+		}
+	}
+
+	for _, attr := range ag.groupBy {
+		add(attr)
+	}
+	for _, aggr := range ag.aggregates {
+		add(aggr.Attribute)
+	}
+	if len(attrs) == 0 {
+		attrs = append(attrs, "__name")
+	}
+
+	return attrs
+}
+
+// Add updates the aggregates with a single item
+func (ag *Aggregator) Add(item map[string]interface{}) {
+	values := make([]interface{}, len(ag.groupBy))
+	keys := make([]string, len(ag.groupBy))
+	for i, attr := range ag.groupBy {
+		values[i] = item[attr]
+		keys[i] = fmt.Sprint(item[attr])
+	}
+
+	key := strings.Join(keys, "\x00")
+	group, ok := ag.groups[key]
+	if !ok {
+		group = &aggrGroup{values: values, states: make([]aggrState, len(ag.aggregates))}
+		ag.groups[key] = group
+	}
+
+	for i, aggr := range ag.aggregates {
+		state := &group.states[i]
+		if aggr.Attribute == "*" {
+			state.count++
+			continue
+		}
+
+		val, ok := item[aggr.Attribute]
+		if !ok || val == nil {
+			continue
+		}
+
+		switch aggr.Function {
+		case "count":
+			state.count++
+		case "sum", "avg":
+			if num, ok := AsFloat(val); ok {
+				state.count++
+				state.sum += num
+			}
+		case "min":
+			if state.min == nil || CompareValues(val, state.min) < 0 {
+				state.min = val
+			}
+		case "max":
+			if state.max == nil || CompareValues(val, state.max) > 0 {
+				state.max = val
+			}
+		}
+	}
+}
+
+// Columns returns the result column names, group-by attributes followed by the aggregates
+func (ag *Aggregator) Columns() []string {
+	columns := append([]string{}, ag.groupBy...)
+	for _, aggr := range ag.aggregates {
+		columns = append(columns, aggr.String())
+	}
+	return columns
+}
+
+// Rows returns a result row per group, sorted by the group-by values
+func (ag *Aggregator) Rows() [][]interface{} {
+	groups := make([]*aggrGroup, 0, len(ag.groups))
+	for _, group := range ag.groups {
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		for k := range ag.groupBy {
+			if cmp := CompareValues(groups[i].values[k], groups[j].values[k]); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+
+	rows := [][]interface{}{}
+	for _, group := range groups {
+		row := append([]interface{}{}, group.values...)
+		for i, aggr := range ag.aggregates {
+			state := group.states[i]
+			switch aggr.Function {
+			case "count":
+				row = append(row, state.count)
+			case "sum":
+				row = append(row, nilIfEmpty(state.count, state.sum))
+			case "avg":
+				if state.count == 0 {
+					row = append(row, nil)
+				} else {
+					row = append(row, state.sum/float64(state.count))
+				}
+			case "min":
+				row = append(row, state.min)
+			case "max":
+				row = append(row, state.max)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func nilIfEmpty(count int, val interface{}) interface{} {
+	if count == 0 {
+		return nil
+	}
+	return val
+}
+
+// AsFloat converts a numeric item value to float64
+func AsFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// CompareValues compares two item values, numbers are compared numerically and anything else by
+// its string form, nil values sort first
+func CompareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if fa, ok := AsFloat(a); ok {
+		if fb, ok := AsFloat(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
@@ -21,10 +21,12 @@ such restriction.
 package utils
 
 import (
+	"fmt"
 	"github.com/nuclio/logger"
 	"github.com/pkg/errors"
 	"github.com/v3io/v3io-go-http"
 	"net/http"
+	"strings"
 )
 
 type ItemsCursor interface {
@@ -35,13 +37,14 @@ type ItemsCursor interface {
 }
 
 type AsyncItemsCursor struct {
-	currentItem  v3io.Item
-	currentError error
-	itemIndex    int
-	items        []v3io.Item
-	input        *v3io.GetItemsInput
-	container    *v3io.Container
-	logger       logger.Logger
+	currentItem    v3io.Item
+	currentError   error
+	currentSegment int
+	itemIndex      int
+	items          []v3io.Item
+	input          *v3io.GetItemsInput
+	container      *v3io.Container
+	logger         logger.Logger
 
 	responseChan  chan *v3io.Response
 	workers       int
@@ -51,15 +54,34 @@ type AsyncItemsCursor struct {
 	limit         int
 }
 
+// SortKeyRange limits a sharding key scan to items whose sorting key is within [Start, End],
+// an empty Start or End leaves that side of the range open
+type SortKeyRange struct {
+	Start string
+	End   string
+}
+
 func NewAsyncItemsCursor(
 	container *v3io.Container, input *v3io.GetItemsInput,
 	workers int, shardingKeys []string, logger logger.Logger, limit int) (*AsyncItemsCursor, error) {
 
+	return NewAsyncItemsCursorWithRange(container, input, workers, shardingKeys, nil, logger, limit)
+}
+
+// NewAsyncItemsCursorWithRange reads the given sharding keys in parallel, limited to the sorting key range
+func NewAsyncItemsCursorWithRange(
+	container *v3io.Container, input *v3io.GetItemsInput,
+	workers int, shardingKeys []string, sortKeyRange *SortKeyRange, logger logger.Logger, limit int) (*AsyncItemsCursor, error) {
+
 	// TODO: use workers from Context.numWorkers (if no ShardingKey)
 	if workers == 0 || input.ShardingKey != "" {
 		workers = 1
 	}
 
+	if len(shardingKeys) == 0 && input.ShardingKey != "" {
+		shardingKeys = []string{input.ShardingKey}
+	}
+
 	newAsyncItemsCursor := &AsyncItemsCursor{
 		container:    container,
 		input:        input,
@@ -76,7 +98,7 @@ func NewAsyncItemsCursor(
 			input := v3io.GetItemsInput{
 				Path:           input.Path,
 				AttributeNames: input.AttributeNames,
-				Filter:         input.Filter,
+				Filter:         sortKeyRange.filter(input.Filter, shardingKeys[i]),
 				ShardingKey:    shardingKeys[i],
 			}
 			_, err := container.GetItems(&input, &input, newAsyncItemsCursor.responseChan)
@@ -109,6 +131,27 @@ func NewAsyncItemsCursor(
 	return newAsyncItemsCursor, nil
 }
 
+// add the sorting key range of a shard to the filter, item names are of the form <sharding-key>.<sorting-key>
+// and are compared as strings, so numeric sorting keys are only ordered when they have a fixed width
+func (r *SortKeyRange) filter(filter, shardingKey string) string {
+	if r == nil {
+		return filter
+	}
+
+	conditions := []string{}
+	if filter != "" {
+		conditions = append(conditions, "("+filter+")")
+	}
+	if r.Start != "" {
+		conditions = append(conditions, fmt.Sprintf("__name >= '%s.%s'", shardingKey, r.Start))
+	}
+	if r.End != "" {
+		conditions = append(conditions, fmt.Sprintf("__name <= '%s.%s'", shardingKey, r.End))
+	}
+
+	return strings.Join(conditions, " and ")
+}
+
 // error returns the last error
 func (ic *AsyncItemsCursor) Err() error {
 	return ic.currentError
@@ -173,15 +216,16 @@ func (ic *AsyncItemsCursor) NextItem() (v3io.Item, error) {
 	}
 
 	getItemsResp := resp.Output.(*v3io.GetItemsOutput)
+	input := resp.Context.(*v3io.GetItemsInput)
 
 	// set the cursor items and reset the item index
 	ic.items = getItemsResp.Items
 	ic.itemIndex = 0
+	ic.currentSegment = input.Segment
 
 	if !getItemsResp.Last {
 
 		// if not last, make a new request to that shard
-		input := resp.Context.(*v3io.GetItemsInput)
 
 		// set next marker
 		input.Marker = getItemsResp.NextMarker
@@ -234,3 +278,13 @@ func (ic *AsyncItemsCursor) GetFields() map[string]interface{} {
 func (ic *AsyncItemsCursor) GetItem() v3io.Item {
 	return ic.currentItem
 }
+
+// GetSegment returns the scan segment the current item was read from
+func (ic *AsyncItemsCursor) GetSegment() int {
+	return ic.currentSegment
+}
+
+// TotalSegments returns the number of segments the scan is split to (0 for sharding key scans)
+func (ic *AsyncItemsCursor) TotalSegments() int {
+	return ic.totalSegments
+}
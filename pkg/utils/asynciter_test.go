@@ -0,0 +1,46 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import "testing"
+
+func TestSortKeyRangeFilter(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		sortRange   *SortKeyRange
+		filter      string
+		shardingKey string
+		expected    string
+	}{
+		{name: "no range", sortRange: nil, filter: "age > 3", shardingKey: "u1", expected: "age > 3"},
+		{name: "empty range", sortRange: &SortKeyRange{}, filter: "", shardingKey: "u1", expected: ""},
+		{name: "start", sortRange: &SortKeyRange{Start: "2018"}, shardingKey: "u1",
+			expected: "__name >= 'u1.2018'"},
+		{name: "end", sortRange: &SortKeyRange{End: "2019"}, shardingKey: "u1",
+			expected: "__name <= 'u1.2019'"},
+		{name: "range with filter", sortRange: &SortKeyRange{Start: "0009", End: "0010"}, filter: "a == 1 or b == 2",
+			shardingKey: "u2", expected: "(a == 1 or b == 2) and __name >= 'u2.0009' and __name <= 'u2.0010'"},
+	} {
+		if filter := test.sortRange.filter(test.filter, test.shardingKey); filter != test.expected {
+			t.Errorf("%s: got %s, expected %s", test.name, filter, test.expected)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTypeHints parses attribute type hints of the form attr=type,
+// the types are int | float | string | blob | time
+func ParseTypeHints(hints []string) (map[string]string, error) {
+	types := map[string]string{}
+	for _, hint := range hints {
+		parts := strings.SplitN(hint, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid type hint '%s', expected attr=type", hint)
+		}
+
+		ftype := strings.ToLower(strings.TrimSpace(parts[1]))
+		switch ftype {
+		case "int", "float", "string", "blob", "time":
+		default:
+			return nil, fmt.Errorf("unsupported type '%s' for %s, use int | float | string | blob | time", ftype, parts[0])
+		}
+		types[strings.TrimSpace(parts[0])] = ftype
+	}
+
+	return types, nil
+}
+
+// DecodeItemJson decodes a json object to item attributes. Numbers without a fraction are kept as int,
+// values may be given in the typed form, e.g. {"age": {"N": "30"}, "img": {"B": "<base64>"}},
+// and type hints convert plain values to the hinted type
+func DecodeItemJson(data []byte, types map[string]string) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	attributes := map[string]interface{}{}
+	for name, val := range raw {
+		var err error
+		if typed, ok := val.(map[string]interface{}); ok {
+			val, err = decodeTypedValue(name, typed)
+		} else if number, ok := val.(json.Number); ok {
+			val, err = decodeNumber(name, number.String())
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if ftype, ok := types[name]; ok {
+			val, err = convertValue(name, val, ftype)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch val.(type) {
+		case int, float64, string, []byte:
+		default:
+			return nil, fmt.Errorf("unsupported value for %s: %v (%T)", name, val, val)
+		}
+		attributes[name] = val
+	}
+
+	return attributes, nil
+}
+
+// decode a value of the form {"N": "30"} | {"S": "foo"} | {"B": "<base64>"}
+func decodeTypedValue(name string, typed map[string]interface{}) (interface{}, error) {
+	if len(typed) != 1 {
+		return nil, fmt.Errorf("typed value for %s must have a single N | S | B key", name)
+	}
+
+	for vtype, val := range typed {
+		str, ok := val.(string)
+		if !ok {
+			if number, isNumber := val.(json.Number); isNumber && vtype == "N" {
+				str = number.String()
+			} else {
+				return nil, fmt.Errorf("typed value for %s must be a string", name)
+			}
+		}
+
+		switch vtype {
+		case "N":
+			return decodeNumber(name, str)
+		case "S":
+			return str, nil
+		case "B":
+			blob, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 blob for %s (%v)", name, err)
+			}
+			return blob, nil
+		default:
+			return nil, fmt.Errorf("unsupported value type %s for %s, use N | S | B", vtype, name)
+		}
+	}
+
+	return nil, nil
+}
+
+func decodeNumber(name, str string) (interface{}, error) {
+	if intValue, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return int(intValue), nil
+	}
+
+	floatValue, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return nil, fmt.Errorf("value for %s is not int or float: %s", name, str)
+	}
+	return floatValue, nil
+}
+
+// convert a decoded value to the hinted type, time values are stored as Unix epoch nanoseconds
+func convertValue(name string, val interface{}, ftype string) (interface{}, error) {
+	switch ftype {
+	case "int":
+		switch v := val.(type) {
+		case int:
+			return v, nil
+		case float64:
+			if v != float64(int(v)) {
+				return nil, fmt.Errorf("value for %s is not an integer: %v", name, v)
+			}
+			return int(v), nil
+		case string:
+			intValue, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value for %s is not an integer: %s", name, v)
+			}
+			return int(intValue), nil
+		}
+	case "float":
+		switch v := val.(type) {
+		case int:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			floatValue, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("value for %s is not a float: %s", name, v)
+			}
+			return floatValue, nil
+		}
+	case "string":
+		switch v := val.(type) {
+		case []byte:
+			return string(v), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case int, float64, string:
+			return fmt.Sprint(v), nil
+		}
+	case "blob":
+		switch v := val.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			blob, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 blob for %s (%v)", name, err)
+			}
+			return blob, nil
+		}
+	case "time":
+		switch v := val.(type) {
+		case int:
+			return v, nil
+		case string:
+			t, err := ParseTime(v, time.Now())
+			if err != nil {
+				return nil, fmt.Errorf("value for %s is not a time: %s", name, v)
+			}
+			return int(t.UnixNano()), nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't convert value for %s (%T) to %s", name, val, ftype)
+}
+
+// ParseTime parses an RFC3339 time, Unix epoch seconds (or milliseconds/nanoseconds by magnitude),
+// or a time relative to now such as -15m, -2h or now
+func ParseTime(str string, now time.Time) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	if str == "now" {
+		return now, nil
+	}
+
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		if d, err := time.ParseDuration(str); err == nil {
+			return now.Add(d), nil
+		}
+		if strings.HasSuffix(str, "d") {
+			if days, err := strconv.Atoi(str[:len(str)-1]); err == nil {
+				return now.Add(time.Duration(days) * 24 * time.Hour), nil
+			}
+		}
+	}
+
+	if epoch, err := strconv.ParseInt(str, 10, 64); err == nil {
+		switch {
+		case epoch > 1e17:
+			return time.Unix(0, epoch), nil
+		case epoch > 1e11:
+			return time.Unix(0, epoch*int64(time.Millisecond)), nil
+		default:
+			return time.Unix(epoch, 0), nil
+		}
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s', use RFC3339, epoch seconds or a relative time like -15m", str)
+	}
+	return t, nil
+}
+
+// FormatItem prepares item attributes for output. Blobs are decoded according to blobMode
+// (int64array | utf8 | hex, empty keeps them as is), and when typed is set every value is tagged with its
+// v3io type in the same form DecodeItemJson accepts, e.g. {"age": {"N": "30"}}, with blobs always kept base64
+func FormatItem(item map[string]interface{}, typed bool, blobMode string) (map[string]interface{}, error) {
+	if !typed && blobMode == "" {
+		return item, nil
+	}
+
+	formatted := make(map[string]interface{}, len(item))
+	for name, val := range item {
+		if blob, ok := val.([]byte); ok && blobMode != "" && !typed {
+			decoded, err := DecodeBlob(blob, blobMode)
+			if err != nil {
+				return nil, err
+			}
+			formatted[name] = decoded
+			continue
+		}
+
+		if !typed {
+			formatted[name] = val
+			continue
+		}
+
+		switch v := val.(type) {
+		case int:
+			formatted[name] = map[string]string{"N": strconv.Itoa(v)}
+		case float64:
+			formatted[name] = map[string]string{"N": FormatFloat(v)}
+		case string:
+			formatted[name] = map[string]string{"S": v}
+		case []byte:
+			formatted[name] = map[string]string{"B": base64.StdEncoding.EncodeToString(v)}
+		default:
+			formatted[name] = val
+		}
+	}
+
+	return formatted, nil
+}
+
+// FormatFloat formats a float keeping a fraction, so the value is read back as a float rather than an int
+func FormatFloat(val float64) string {
+	str := strconv.FormatFloat(val, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eEnN") {
+		str += ".0"
+	}
+	return str
+}
+
+// DecodeBlob decodes a blob as an array of integers (int64array), text (utf8) or hex digits (hex)
+func DecodeBlob(blob []byte, mode string) (interface{}, error) {
+	switch mode {
+	case "int64array":
+		return AsInt64Array(blob), nil
+	case "utf8":
+		return string(blob), nil
+	case "hex":
+		return hex.EncodeToString(blob), nil
+	}
+
+	return nil, fmt.Errorf("blob decoding %s is invalid, use int64array | utf8 | hex", mode)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTypeHints(t *testing.T) {
+	for _, test := range []struct {
+		hints []string
+		types map[string]string
+		fail  bool
+	}{
+		{hints: []string{}, types: map[string]string{}},
+		{hints: []string{"age=int", " score = Float ", "img=blob", "at=time", "id=string"},
+			types: map[string]string{"age": "int", "score": "float", "img": "blob", "at": "time", "id": "string"}},
+		{hints: []string{"age"}, fail: true},
+		{hints: []string{"=int"}, fail: true},
+		{hints: []string{"age=long"}, fail: true},
+	} {
+		types, err := ParseTypeHints(test.hints)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%v: expected an error, got %v", test.hints, types)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(types, test.types) {
+			t.Errorf("%v: got %v (%v), expected %v", test.hints, types, err, test.types)
+		}
+	}
+}
+
+func TestDecodeItemJson(t *testing.T) {
+	at := time.Date(2018, 6, 1, 22, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name  string
+		json  string
+		types map[string]string
+		item  map[string]interface{}
+		fail  bool
+	}{
+		{name: "plain values", json: `{"a": 30, "b": 1.5, "c": "x", "d": 12345678901234}`,
+			item: map[string]interface{}{"a": 30, "b": 1.5, "c": "x", "d": 12345678901234}},
+		{name: "typed values", json: `{"a": {"N": "30"}, "b": {"N": 2.5}, "c": {"S": "7"}, "d": {"B": "AQI="}}`,
+			item: map[string]interface{}{"a": 30, "b": 2.5, "c": "7", "d": []byte{1, 2}}},
+		{name: "type hints", json: `{"a": "30", "b": 2, "c": 5, "d": "AQI=", "e": "2018-06-01T22:00:00Z", "f": 3.0}`,
+			types: map[string]string{"a": "int", "b": "float", "c": "string", "d": "blob", "e": "time", "f": "int"},
+			item:  map[string]interface{}{"a": 30, "b": 2.0, "c": "5", "d": []byte{1, 2}, "e": int(at.UnixNano()), "f": 3}},
+		{name: "bool as string", json: `{"a": true}`, types: map[string]string{"a": "string"},
+			item: map[string]interface{}{"a": "true"}},
+		{name: "bool", json: `{"a": true}`, fail: true},
+		{name: "null", json: `{"a": null}`, fail: true},
+		{name: "nested", json: `{"a": [1]}`, fail: true},
+		{name: "two typed keys", json: `{"a": {"N": "1", "S": "1"}}`, fail: true},
+		{name: "unknown typed key", json: `{"a": {"BOOL": "true"}}`, fail: true},
+		{name: "bad number", json: `{"a": {"N": "x"}}`, fail: true},
+		{name: "bad blob", json: `{"a": {"B": "!"}}`, fail: true},
+		{name: "fraction as int", json: `{"a": 1.5}`, types: map[string]string{"a": "int"}, fail: true},
+		{name: "not an object", json: `[1]`, fail: true},
+	} {
+		item, err := DecodeItemJson([]byte(test.json), test.types)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", test.name, item)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(item, test.item) {
+			t.Errorf("%s: got %v (%v), expected %v", test.name, item, err, test.item)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		str      string
+		expected time.Time
+		fail     bool
+	}{
+		{str: "now", expected: now},
+		{str: "-15m", expected: now.Add(-15 * time.Minute)},
+		{str: "+2h", expected: now.Add(2 * time.Hour)},
+		{str: "-1d", expected: now.Add(-24 * time.Hour)},
+		{str: "1527854400", expected: time.Unix(1527854400, 0)},
+		{str: "1527854400123", expected: time.Unix(1527854400, 123*int64(time.Millisecond))},
+		{str: "1527854400000000123", expected: time.Unix(1527854400, 123)},
+		{str: " 2018-06-01T22:00:00Z ", expected: time.Date(2018, 6, 1, 22, 0, 0, 0, time.UTC)},
+		{str: "2018-06-01T22:00:00.5+02:00", expected: time.Date(2018, 6, 1, 20, 0, 0, 5e8, time.UTC)},
+		{str: "-1x", fail: true},
+		{str: "yesterday", fail: true},
+		{str: "2018-06-01", fail: true},
+	} {
+		parsed, err := ParseTime(test.str, now)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", test.str, parsed)
+			}
+			continue
+		}
+		if err != nil || !parsed.Equal(test.expected) {
+			t.Errorf("%s: got %v (%v), expected %v", test.str, parsed, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"github.com/v3io/v3io-go-http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const shardAttributePrefix = "shard_"
+
+// Checkpoints keeps the committed sequence number of every shard for a consumer group in a KV item.
+// The consumer holding the item lease is the owner, and updates are conditioned on the ownership so two
+// consumers of the same group don't overwrite each other
+type Checkpoints struct {
+	container *v3io.Container
+	itemPath  string
+	owner     string
+	lease     time.Duration
+}
+
+func NewCheckpoints(container *v3io.Container, itemPath, owner string, lease time.Duration) *Checkpoints {
+	return &Checkpoints{container: container, itemPath: itemPath, owner: owner, lease: lease}
+}
+
+// Acquire takes the ownership of the group (when not owned or the lease expired) and returns the committed
+// sequence number per shard
+func (cp *Checkpoints) Acquire() (map[int]int, error) {
+	expression := cp.leaseExpression()
+	condition := fmt.Sprintf("not exists(owner) or owner == '%s' or lease_expires < %d", cp.owner, time.Now().Unix())
+	err := cp.container.Sync.UpdateItem(&v3io.UpdateItemInput{Path: cp.itemPath, Expression: &expression, Condition: condition})
+	if err != nil {
+		if IsConditionFailed(err) {
+			return nil, fmt.Errorf("the consumer group is owned by another consumer, try again when its lease expires")
+		}
+		return nil, fmt.Errorf("failed to acquire the consumer group %s (%v)", cp.itemPath, err)
+	}
+
+	resp, err := cp.container.Sync.GetItem(&v3io.GetItemInput{Path: cp.itemPath, AttributeNames: []string{"*"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the checkpoints from %s (%v)", cp.itemPath, err)
+	}
+	defer resp.Release()
+
+	positions := map[int]int{}
+	for name, val := range resp.Output.(*v3io.GetItemOutput).Item {
+		if !strings.HasPrefix(name, shardAttributePrefix) {
+			continue
+		}
+		shard, err := strconv.Atoi(name[len(shardAttributePrefix):])
+		if err != nil {
+			continue
+		}
+		if seq, ok := val.(int); ok {
+			positions[shard] = seq
+		}
+	}
+
+	return positions, nil
+}
+
+// Commit stores the sequence numbers of the shards and renews the lease
+func (cp *Checkpoints) Commit(positions map[int]int) error {
+	shards := make([]int, 0, len(positions))
+	for shard := range positions {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+
+	statements := []string{cp.leaseExpression()}
+	for _, shard := range shards {
+		statements = append(statements, fmt.Sprintf("%s%d=%d", shardAttributePrefix, shard, positions[shard]))
+	}
+
+	return cp.update(strings.Join(statements, "; "))
+}
+
+// Release ends the lease so another consumer may take over the group right away
+func (cp *Checkpoints) Release() error {
+	return cp.update("lease_expires=0")
+}
+
+func (cp *Checkpoints) update(expression string) error {
+	condition := fmt.Sprintf("owner == '%s'", cp.owner)
+	err := cp.container.Sync.UpdateItem(&v3io.UpdateItemInput{Path: cp.itemPath, Expression: &expression, Condition: condition})
+	if err != nil {
+		if IsConditionFailed(err) {
+			return fmt.Errorf("the consumer group was taken over by another consumer")
+		}
+		return fmt.Errorf("failed to update the checkpoints in %s (%v)", cp.itemPath, err)
+	}
+	return nil
+}
+
+func (cp *Checkpoints) leaseExpression() string {
+	return fmt.Sprintf("owner='%s'; lease_expires=%d", cp.owner, time.Now().Add(cp.lease).Unix())
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"github.com/v3io/v3io-go-http"
+	"sync"
+	"time"
+)
+
+type ConsumerConfig struct {
+	// KV item holding the consumer group checkpoints
+	CheckpointPath string
+	// consumer id, the owner of the group while it holds the lease
+	Owner          string
+	Lease          time.Duration
+	CommitInterval time.Duration
+	// also commit after every CommitBatch processed records (zero commits only by interval)
+	CommitBatch int
+	// position of shards without a checkpoint
+	Start v3io.SeekShardInput
+	// when set the checkpoints are moved to this position before reading
+	Reset  *v3io.SeekShardInput
+	Reader StreamReaderConfig
+}
+
+// Consumer reads the shards of a stream as a member of a consumer group, resuming from the group checkpoints.
+// Processed records are marked with Done and committed periodically, so records are delivered at least once
+type Consumer struct {
+	config      ConsumerConfig
+	checkpoints *Checkpoints
+	reader      *StreamReader
+	lock        sync.Mutex
+	committed   map[int]int
+	processed   int
+	commitErr   error
+	done        chan struct{}
+}
+
+func NewConsumer(container *v3io.Container, shards []StreamShard, config ConsumerConfig) (*Consumer, error) {
+	checkpoints := NewCheckpoints(container, config.CheckpointPath, config.Owner, config.Lease)
+	positions, err := checkpoints.Acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	// a reset moves the checkpoints to the reset position right away, so it also holds for the next run
+	if config.Reset != nil {
+		for _, shard := range shards {
+			if positions[shard.ID], err = SequenceBefore(container, shard, *config.Reset); err != nil {
+				checkpoints.Release()
+				return nil, err
+			}
+		}
+		if err := checkpoints.Commit(positions); err != nil {
+			checkpoints.Release()
+			return nil, err
+		}
+	}
+
+	consumer := Consumer{config: config, checkpoints: checkpoints, committed: positions, done: make(chan struct{})}
+	consumer.reader = NewStreamReader(container, shards,
+		func(shard StreamShard) v3io.SeekShardInput {
+			seq, ok := positions[shard.ID]
+			switch {
+			case !ok:
+				return config.Start
+			case seq == 0:
+				return v3io.SeekShardInput{Type: v3io.SeekShardInputTypeEarliest}
+			}
+			return v3io.SeekShardInput{Type: v3io.SeekShardInputTypeSequence, StartingSequenceNumber: seq + 1}
+		}, config.Reader)
+
+	// commits run on a timer so the lease is renewed while waiting for records
+	go func() {
+		ticker := time.NewTicker(config.CommitInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				consumer.Commit()
+			case <-consumer.done:
+				return
+			}
+		}
+	}()
+
+	return &consumer, nil
+}
+
+func (c *Consumer) Next() bool {
+	return c.reader.Next()
+}
+
+func (c *Consumer) Record() *ShardRecord {
+	return c.reader.Record()
+}
+
+// Done marks a record as processed, its sequence number is committed with the next commit
+func (c *Consumer) Done(record *ShardRecord) error {
+	c.lock.Lock()
+	c.committed[record.Shard] = record.SequenceNumber
+	c.processed++
+	batchFull := c.config.CommitBatch > 0 && c.processed%c.config.CommitBatch == 0
+	c.lock.Unlock()
+
+	if batchFull {
+		return c.Commit()
+	}
+	return nil
+}
+
+// Commit stores the processed positions and renews the lease, once the group was taken over by another
+// consumer reading stops and every commit fails
+func (c *Consumer) Commit() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.commitErr != nil {
+		return c.commitErr
+	}
+	if c.commitErr = c.checkpoints.Commit(c.committed); c.commitErr != nil {
+		c.reader.Close()
+	}
+	return c.commitErr
+}
+
+// Stop ends reading, it may be called from another goroutine (e.g. on interrupt) to end a blocked Next
+func (c *Consumer) Stop() {
+	c.reader.Close()
+}
+
+func (c *Consumer) Err() error {
+	return c.reader.Err()
+}
+
+// Close commits the processed records and releases the group
+func (c *Consumer) Close() error {
+	c.reader.Close()
+	close(c.done)
+
+	err := c.Commit()
+	c.checkpoints.Release()
+	return err
+}
@@ -22,13 +22,16 @@ package utils
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/pkg/errors"
 	"github.com/v3io/v3io-go-http"
+	"io"
+	"net/http"
 	"net/url"
-	"time"
+	"strings"
 )
 
 func NewLogger(level string) (logger.Logger, error) {
@@ -87,76 +90,181 @@ func AsInt64Array(val []byte) []uint64 {
 }
 
 func DeleteTable(logger logger.Logger, container *v3io.Container, path, filter string, workers int) error {
-	input := v3io.GetItemsInput{Path: path, AttributeNames: []string{"__name"}, Filter: filter}
-	iter, err := NewAsyncItemsCursor(container, &input, workers, []string{}, logger, 0)
+	failed, err := DeleteItems(logger, container, path, filter, workers, nil, nil)
 	if err != nil {
 		return err
 	}
 
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to delete %d items:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+
+	return nil
+}
+
+// DeleteItems deletes the items matching the filter and returns the keys which failed to delete. When backup
+// is set every item is written to it as a typed json line (see FormatItem) before it's deleted, and progress
+// is called after every response with the number of deleted and failed items
+func DeleteItems(logger logger.Logger, container *v3io.Container, path, filter string, workers int,
+	backup io.Writer, progress func(deleted, failed int)) ([]string, error) {
+
+	attrs := []string{"__name"}
+	if backup != nil {
+		attrs = append(attrs, "*")
+	}
+
+	input := v3io.GetItemsInput{Path: path, AttributeNames: attrs, Filter: filter}
+	iter, err := NewAsyncItemsCursor(container, &input, workers, []string{}, logger, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := 0
+	failed := []string{}
 	responseChan := make(chan *v3io.Response, 1000)
 	commChan := make(chan int, 2)
-	doneChan := respWaitLoop(commChan, responseChan, 10*time.Second)
-	reqMap := map[uint64]bool{}
+	doneChan := WaitResponses(commChan, responseChan, func(resp *v3io.Response) {
+		if resp.Error != nil {
+			logger.WarnWith("Failed to delete item", "key", resp.Context, "err", resp.Error)
+			failed = append(failed, resp.Context.(string))
+		} else {
+			deleted++
+		}
+		if progress != nil {
+			progress(deleted, len(failed))
+		}
+	})
 
 	i := 0
 	for iter.Next() {
 		name := iter.GetField("__name").(string)
-		req, err := container.DeleteObject(&v3io.DeleteObjectInput{Path: path + "/" + url.QueryEscape(name)}, nil, responseChan)
+
+		if backup != nil {
+			item, err := FormatItem(iter.GetFields(), true, "")
+			if err == nil {
+				var body []byte
+				body, err = json.Marshal(item)
+				if err == nil {
+					_, err = fmt.Fprintf(backup, "%s\n", body)
+				}
+			}
+			if err != nil {
+				commChan <- i
+				<-doneChan
+				return failed, errors.Wrapf(err, "Failed to backup item '%s', stopped deleting.", name)
+			}
+		}
+
+		_, err := container.DeleteObject(&v3io.DeleteObjectInput{Path: path + url.QueryEscape(name)}, name, responseChan)
 		if err != nil {
 			commChan <- i
-			return errors.Wrapf(err, "Failed to delete object '%s'.", name)
+			<-doneChan
+			return failed, errors.Wrapf(err, "Failed to delete object '%s'.", name)
 		}
-		reqMap[req.ID] = true
 		i++
 	}
 
 	commChan <- i
+	<-doneChan
+
 	if iter.Err() != nil {
-		return errors.Wrap(iter.Err(), "Failed to delete object.")
+		return failed, errors.Wrap(iter.Err(), "Failed to delete object.")
 	}
 
+	return failed, nil
+}
+
+type UpdateItemsResult struct {
+	Updated int
+	Skipped int
+	Failed  int
+}
+
+// UpdateItems applies an update expression to every item matching the filter, items which
+// fail the update condition are counted as skipped
+func UpdateItems(logger logger.Logger, container *v3io.Container, path, filter, expression, condition string,
+	workers int) (*UpdateItemsResult, error) {
+
+	input := v3io.GetItemsInput{Path: path, AttributeNames: []string{"__name"}, Filter: filter}
+	iter, err := NewAsyncItemsCursor(container, &input, workers, []string{}, logger, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := UpdateItemsResult{}
+	responseChan := make(chan *v3io.Response, 1000)
+	commChan := make(chan int, 2)
+	doneChan := WaitResponses(commChan, responseChan, func(resp *v3io.Response) {
+		switch {
+		case resp.Error == nil:
+			result.Updated++
+		case IsConditionFailed(resp.Error):
+			result.Skipped++
+		default:
+			result.Failed++
+			logger.WarnWith("UpdateItem failed", "path", resp.Context, "err", resp.Error)
+		}
+	})
+
+	i := 0
+	for iter.Next() {
+		itemPath := path + url.QueryEscape(iter.GetField("__name").(string))
+		_, err := container.UpdateItem(&v3io.UpdateItemInput{
+			Path: itemPath, Expression: &expression, Condition: condition}, itemPath, responseChan)
+		if err != nil {
+			commChan <- i
+			<-doneChan
+			return &result, errors.Wrapf(err, "Failed to update item '%s'.", itemPath)
+		}
+		i++
+	}
+
+	commChan <- i
 	<-doneChan
 
-	return nil
+	if iter.Err() != nil {
+		return &result, errors.Wrap(iter.Err(), "Failed to read items.")
+	}
+
+	return &result, nil
+}
+
+// IsConditionFailed returns true if the request was rejected because its condition wasn't met
+func IsConditionFailed(err error) bool {
+	return hasStatusCode(err, http.StatusPreconditionFailed)
+}
+
+// IsNotFound returns true if the request failed because the object or item doesn't exist
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
 }
 
-func respWaitLoop(comm chan int, responseChan chan *v3io.Response, timeout time.Duration) chan bool {
+func hasStatusCode(err error, statusCode int) bool {
+	e, hasErrorCode := errors.Cause(err).(v3io.ErrorWithStatusCode)
+	return hasErrorCode && e.StatusCode() == statusCode
+}
+
+// WaitResponses passes every response to the handler, and signals done once the number of
+// requests reported on the comm channel were answered
+func WaitResponses(comm chan int, responseChan chan *v3io.Response, handler func(resp *v3io.Response)) chan bool {
 	responses := 0
 	requests := -1
 	done := make(chan bool)
 
 	go func() {
-		active := false
 		for {
-			select {
+			if requests >= 0 && responses >= requests {
+				done <- true
+				return
+			}
 
+			select {
 			case resp := <-responseChan:
 				responses++
-				active = true
-
-				if resp.Error != nil {
-					fmt.Println(resp.Error, "Failed to receive a response to delete request.")
-				}
-
-				if requests == responses {
-					done <- true
-					return
-				}
+				handler(resp)
+				resp.Release()
 
 			case requests = <-comm:
-				if requests <= responses {
-					done <- true
-					return
-				}
-
-			case <-time.After(timeout):
-				if !active {
-					fmt.Println("\nResponse loop timed out.", requests, responses)
-					done <- true
-					return
-				} else {
-					active = false
-				}
 			}
 		}
 	}()
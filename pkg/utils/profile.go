@@ -0,0 +1,242 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const hllPrecision = 12
+
+// HyperLogLog is an approximate distinct counter (about 1.6% standard error)
+type HyperLogLog struct {
+	registers []uint8
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+func (h *HyperLogLog) Add(data []byte) {
+	hash := fnv.New64a()
+	hash.Write(data)
+	x := mix64(hash.Sum64())
+
+	idx := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// use linear counting for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// spread the fnv hash bits (splitmix64 finalizer), fnv alone is biased in the high bits
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// TopK tracks the most frequent values with the space-saving algorithm, counts are upper bounds
+// once more distinct values than the tracked capacity were seen
+type TopK struct {
+	k        int
+	capacity int
+	counts   map[string]int
+}
+
+func NewTopK(k int) *TopK {
+	return &TopK{k: k, capacity: k * 10, counts: map[string]int{}}
+}
+
+func (t *TopK) Add(value string) {
+	if _, ok := t.counts[value]; ok || len(t.counts) < t.capacity {
+		t.counts[value]++
+		return
+	}
+
+	// replace the least frequent value
+	minValue, minCount := "", math.MaxInt64
+	for val, count := range t.counts {
+		if count < minCount {
+			minValue, minCount = val, count
+		}
+	}
+	delete(t.counts, minValue)
+	t.counts[value] = minCount + 1
+}
+
+func (t *TopK) Top() []ValueCount {
+	top := make([]ValueCount, 0, len(t.counts))
+	for val, count := range t.counts {
+		top = append(top, ValueCount{Value: val, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Value < top[j].Value
+	})
+	if len(top) > t.k {
+		top = top[:t.k]
+	}
+
+	return top
+}
+
+// AttributeProfile holds the statistics of a single attribute
+type AttributeProfile struct {
+	Name     string         `json:"name"`
+	Types    map[string]int `json:"types"`
+	Count    int            `json:"count"`
+	Missing  int            `json:"missing"`
+	Min      interface{}    `json:"min,omitempty"`
+	Max      interface{}    `json:"max,omitempty"`
+	Mean     *float64       `json:"mean,omitempty"`
+	Stddev   *float64       `json:"stddev,omitempty"`
+	Distinct uint64         `json:"distinct"`
+	Top      []ValueCount   `json:"top,omitempty"`
+
+	numeric  int
+	mean     float64
+	m2       float64
+	distinct *HyperLogLog
+	top      *TopK
+}
+
+// Profiler collects per attribute statistics from a stream of items
+type Profiler struct {
+	topK       int
+	rows       int
+	attributes map[string]*AttributeProfile
+}
+
+func NewProfiler(topK int) *Profiler {
+	return &Profiler{topK: topK, attributes: map[string]*AttributeProfile{}}
+}
+
+func (p *Profiler) Add(item map[string]interface{}) {
+	p.rows++
+	for name, val := range item {
+		attr, ok := p.attributes[name]
+		if !ok {
+			attr = &AttributeProfile{Name: name, Types: map[string]int{}, distinct: NewHyperLogLog()}
+			if p.topK > 0 {
+				attr.top = NewTopK(p.topK)
+			}
+			p.attributes[name] = attr
+		}
+		attr.add(val)
+	}
+}
+
+func (a *AttributeProfile) add(val interface{}) {
+	a.Count++
+	vtype := ValueType(val)
+	if vtype == "" {
+		vtype = fmt.Sprintf("%T", val)
+	}
+	a.Types[vtype]++
+
+	if a.Min == nil || CompareValues(val, a.Min) < 0 {
+		a.Min = val
+	}
+	if a.Max == nil || CompareValues(val, a.Max) > 0 {
+		a.Max = val
+	}
+
+	// running mean and variance (Welford)
+	if num, ok := AsFloat(val); ok {
+		a.numeric++
+		delta := num - a.mean
+		a.mean += delta / float64(a.numeric)
+		a.m2 += delta * (num - a.mean)
+	}
+
+	// tag values with their type so 1 and "1" are counted as distinct
+	str := fmt.Sprint(val)
+	if blob, ok := val.([]byte); ok {
+		str = string(blob)
+	}
+	a.distinct.Add([]byte(vtype + ":" + str))
+	if a.top != nil {
+		a.top.Add(str)
+	}
+}
+
+func (p *Profiler) Rows() int {
+	return p.rows
+}
+
+// Profiles returns the attribute statistics sorted by attribute name
+func (p *Profiler) Profiles() []*AttributeProfile {
+	profiles := make([]*AttributeProfile, 0, len(p.attributes))
+	for _, attr := range p.attributes {
+		attr.Missing = p.rows - attr.Count
+		attr.Distinct = attr.distinct.Count()
+		if attr.top != nil {
+			attr.Top = attr.top.Top()
+		}
+		if attr.numeric > 0 {
+			mean, stddev := attr.mean, math.Sqrt(attr.m2/float64(attr.numeric))
+			attr.Mean, attr.Stddev = &mean, &stddev
+		}
+		// blobs are not useful as min/max
+		if _, ok := attr.Min.([]byte); ok {
+			attr.Min, attr.Max = nil, nil
+		}
+		profiles = append(profiles, attr)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+
+	return profiles
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, test := range []struct {
+		distinct  int
+		repeat    int
+		tolerance float64
+	}{
+		{distinct: 0, repeat: 1, tolerance: 0},
+		{distinct: 1, repeat: 5, tolerance: 0},
+		{distinct: 100, repeat: 3, tolerance: 0.02},
+		{distinct: 10000, repeat: 2, tolerance: 0.05},
+		{distinct: 200000, repeat: 1, tolerance: 0.05},
+	} {
+		hll := NewHyperLogLog()
+		for r := 0; r < test.repeat; r++ {
+			for i := 0; i < test.distinct; i++ {
+				hll.Add([]byte(fmt.Sprintf("value-%d", i)))
+			}
+		}
+
+		count := float64(hll.Count())
+		if diff := count - float64(test.distinct); diff > test.tolerance*float64(test.distinct) ||
+			-diff > test.tolerance*float64(test.distinct) {
+			t.Errorf("%d distinct values: got %v, expected within %v%%", test.distinct, count, test.tolerance*100)
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		k        int
+		values   []string
+		expected []ValueCount
+	}{
+		{name: "empty", k: 3, values: []string{}, expected: []ValueCount{}},
+		{name: "ordered by count then value", k: 3, values: []string{"b", "a", "c", "a", "b", "a", "d"},
+			expected: []ValueCount{{"a", 3}, {"b", 2}, {"c", 1}}},
+		{name: "fewer values than k", k: 5, values: []string{"x", "y", "x"},
+			expected: []ValueCount{{"x", 2}, {"y", 1}}},
+	} {
+		top := NewTopK(test.k)
+		for _, val := range test.values {
+			top.Add(val)
+		}
+		if got := top.Top(); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.name, got, test.expected)
+		}
+	}
+
+	// frequent values are kept when more distinct values than the capacity are seen
+	top := NewTopK(2)
+	for i := 0; i < 1000; i++ {
+		top.Add(fmt.Sprintf("rare-%d", i))
+		if i%4 == 0 {
+			top.Add("hot")
+		}
+		if i%10 == 0 {
+			top.Add("warm")
+		}
+	}
+	got := top.Top()
+	if len(got) != 2 || got[0].Value != "hot" || got[1].Value != "warm" || got[0].Count < 250 || got[1].Count < 100 {
+		t.Errorf("overflow: got %v, expected hot (>= 250) and warm (>= 100)", got)
+	}
+}
@@ -26,6 +26,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/v3io/frames"
 	"github.com/v3io/v3io-go-http"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -39,10 +43,28 @@ func SchemaFromJson(data []byte) (V3ioSchema, error) {
 	return &schema, err
 }
 
+// LoadSchema reads the schema stored in the table directory (tablePath must end with a slash)
+func LoadSchema(container *v3io.Container, tablePath string) (*OldV3ioSchema, error) {
+	resp, err := container.Sync.GetObject(&v3io.GetObjectInput{Path: tablePath + ".%23schema"})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read schema")
+	}
+	defer resp.Release()
+
+	schema := OldV3ioSchema{}
+	if err := json.Unmarshal(resp.Body(), &schema); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal schema")
+	}
+
+	return &schema, nil
+}
+
 type V3ioSchema interface {
 	AddColumn(name string, col frames.Column, nullable bool) error
 	AddField(name string, val interface{}, nullable bool) error
 	UpdateSchema(container *v3io.Container, tablePath string, newSchema V3ioSchema) error
+	Validate(attributes map[string]interface{}, strict bool) error
+	ValidateUpdate(expression string, strict bool) error
 	ToJson() ([]byte, error)
 }
 
@@ -79,23 +101,106 @@ func (s *OldV3ioSchema) AddColumn(name string, col frames.Column, nullable bool)
 
 func (s *OldV3ioSchema) AddField(name string, val interface{}, nullable bool) error {
 
-	var ftype string
+	field := OldSchemaField{Name: name, Type: ValueType(val), Nullable: nullable}
+	s.Fields = append(s.Fields, field)
+	return nil
+}
+
+// ValueType returns the schema type of an item value
+func ValueType(val interface{}) string {
 	switch val.(type) {
 	case int, int32, int64:
-		ftype = "long"
+		return "long"
 	case float32, float64:
-		ftype = "double"
+		return "double"
 	case string:
-		ftype = "string"
+		return "string"
 	case time.Time:
-		ftype = "time"
+		return "time"
+	case bool:
+		return "boolean"
+	case []byte:
+		return "blob"
+	}
+	return ""
+}
+
+// combine the types of values seen in the same attribute, mixed numbers are double and other mixes are string
+func combineTypes(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	case (a == "long" && b == "double") || (a == "double" && b == "long"):
+		return "double"
+	}
+	return "string"
+}
+
+type inferredField struct {
+	ftype string
+	count int
+}
+
+// SchemaInferrer builds a schema from sampled items, the field types are combined from all the values seen
+// and fields missing from any of the items are nullable
+type SchemaInferrer struct {
+	key    string
+	rows   int
+	fields map[string]*inferredField
+	names  []string
+}
+
+func NewSchemaInferrer(key string) *SchemaInferrer {
+	return &SchemaInferrer{key: key, fields: map[string]*inferredField{}}
+}
+
+func (si *SchemaInferrer) Add(item map[string]interface{}) error {
+	if _, ok := item[si.key]; !ok {
+		return fmt.Errorf("key (%s) was not found in row", si.key)
+	}
+
+	si.rows++
+	for name, val := range item {
+		field, ok := si.fields[name]
+		if !ok {
+			field = &inferredField{}
+			si.fields[name] = field
+			si.names = append(si.names, name)
+		}
+		if val != nil {
+			field.ftype = combineTypes(field.ftype, ValueType(val))
+			field.count++
+		}
 	}
 
-	field := OldSchemaField{Name: name, Type: ftype, Nullable: nullable}
-	s.Fields = append(s.Fields, field)
 	return nil
 }
 
+// Rows returns the number of items added
+func (si *SchemaInferrer) Rows() int {
+	return si.rows
+}
+
+func (si *SchemaInferrer) Schema(hashingBuckets int) *OldV3ioSchema {
+	schema := &OldV3ioSchema{Fields: []OldSchemaField{}, Key: si.key, HashingBucketNum: hashingBuckets}
+	names := append([]string{}, si.names...)
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := si.fields[name]
+		ftype := field.ftype
+		if ftype == "" {
+			ftype = "string"
+		}
+		schema.Fields = append(schema.Fields,
+			OldSchemaField{Name: name, Type: ftype, Nullable: field.count < si.rows && name != si.key})
+	}
+
+	return schema
+}
+
 func (s *OldV3ioSchema) ToJson() ([]byte, error) {
 	return json.Marshal(s)
 }
@@ -148,16 +253,235 @@ func (s *OldV3ioSchema) UpdateSchema(container *v3io.Container, tablePath string
 	}
 
 	if changed {
-		body, err := s.ToJson()
-		if err != nil {
-			return errors.Wrap(err, "failed to marshal schema")
+		return s.Save(container, tablePath)
+	}
+
+	return nil
+}
+
+// Save writes the schema to the table directory (tablePath must end with a slash)
+func (s *OldV3ioSchema) Save(container *v3io.Container, tablePath string) error {
+	body, err := s.ToJson()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal schema")
+	}
+	err = container.Sync.PutObject(&v3io.PutObjectInput{
+		Path: tablePath + ".%23schema", Body: body})
+	if err != nil {
+		return errors.Wrap(err, "failed to update schema")
+	}
+
+	return nil
+}
+
+// CheckCompatible returns an error if a field type changes in a way merge (and so UpdateSchema) doesn't allow,
+// the merge rules are applied to a copy of the schema
+func (s *OldV3ioSchema) CheckCompatible(new *OldV3ioSchema) error {
+	merged := *s
+	merged.Fields = append([]OldSchemaField{}, s.Fields...)
+	_, err := merged.merge(new)
+	return err
+}
+
+// Diff lists the changes from this schema to the new one
+func (s *OldV3ioSchema) Diff(new *OldV3ioSchema) []string {
+	changes := []string{}
+	for _, field := range new.Fields {
+		old := s.field(field.Name)
+		switch {
+		case old == nil:
+			changes = append(changes, fmt.Sprintf("+ %s %s%s", field.Name, field.Type, nullableString(field.Nullable)))
+		case old.Type != field.Type || old.Nullable != field.Nullable:
+			changes = append(changes, fmt.Sprintf("~ %s %s%s -> %s%s", field.Name,
+				old.Type, nullableString(old.Nullable), field.Type, nullableString(field.Nullable)))
 		}
-		err = container.Sync.PutObject(&v3io.PutObjectInput{
-			Path: tablePath + ".%23schema", Body: body})
-		if err != nil {
-			return errors.Wrap(err, "failed to update schema")
+	}
+
+	for _, field := range s.Fields {
+		if new.field(field.Name) == nil {
+			changes = append(changes, fmt.Sprintf("- %s %s%s", field.Name, field.Type, nullableString(field.Nullable)))
 		}
 	}
 
+	if s.Key != new.Key {
+		changes = append(changes, fmt.Sprintf("~ key %s -> %s", s.Key, new.Key))
+	}
+	if s.HashingBucketNum != new.HashingBucketNum {
+		changes = append(changes, fmt.Sprintf("~ hashingBucketNum %d -> %d", s.HashingBucketNum, new.HashingBucketNum))
+	}
+
+	return changes
+}
+
+func nullableString(nullable bool) string {
+	if nullable {
+		return " (nullable)"
+	}
+	return ""
+}
+
+// Validate checks the attributes of a complete item against the schema, values must match the field types and
+// non-nullable fields must be present, with strict attributes which are not in the schema are rejected
+func (s *OldV3ioSchema) Validate(attributes map[string]interface{}, strict bool) error {
+	problems := s.validateValues(attributes, strict)
+
+	for _, field := range s.Fields {
+		if _, ok := attributes[field.Name]; !ok && !field.Nullable && !strings.HasPrefix(field.Name, "__") {
+			problems = append(problems, fmt.Sprintf("missing non-nullable attribute %s", field.Name))
+		}
+	}
+
+	return validationError(problems)
+}
+
+// ValidateUpdate checks an update expression against the schema, literal values assigned to attributes must
+// match the field types and non-nullable fields can't be removed
+func (s *OldV3ioSchema) ValidateUpdate(expression string, strict bool) error {
+	values, removed, err := parseUpdateExpression(expression)
+	if err != nil {
+		return err
+	}
+	problems := s.validateValues(values, strict)
+
+	for _, name := range removed {
+		if field := s.field(name); field != nil && !field.Nullable {
+			problems = append(problems, fmt.Sprintf("can't remove non-nullable attribute %s", name))
+		}
+	}
+
+	return validationError(problems)
+}
+
+func (s *OldV3ioSchema) field(name string) *OldSchemaField {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i]
+		}
+	}
 	return nil
 }
+
+// check the value types, nil values are of an unknown type and are not checked
+func (s *OldV3ioSchema) validateValues(attributes map[string]interface{}, strict bool) []string {
+	problems := []string{}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := s.field(name)
+		if field == nil {
+			if strict && !strings.HasPrefix(name, "__") {
+				problems = append(problems, fmt.Sprintf("attribute %s is not in the schema", name))
+			}
+			continue
+		}
+
+		val := attributes[name]
+		if val != nil && !valueMatchesType(val, field.Type) {
+			problems = append(problems, fmt.Sprintf("attribute %s is of type %s, got %v (%T)", name, field.Type, val, val))
+		}
+	}
+
+	return problems
+}
+
+func valueMatchesType(val interface{}, ftype string) bool {
+	switch val.(type) {
+	case int:
+		return ftype == "long" || ftype == "double" || ftype == "time"
+	case float64:
+		return ftype == "double"
+	case string:
+		return ftype == "string"
+	case []byte:
+		return ftype == "blob"
+	case bool:
+		return ftype == "boolean"
+	}
+	return false
+}
+
+func validationError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("schema validation failed:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// CheckUpdateExpression returns an error if a literal value in an update expression is invalid
+func CheckUpdateExpression(expression string) error {
+	_, _, err := parseUpdateExpression(expression)
+	return err
+}
+
+// parse an update expression such as "x=5; SET y='good'; z=z+1; REMOVE w" to the assigned attributes and the
+// removed attributes, literal values are decoded and other expressions are returned as nil
+func parseUpdateExpression(expression string) (map[string]interface{}, []string, error) {
+	values := map[string]interface{}{}
+	removed := []string{}
+
+	for _, statement := range splitOutsideQuotes(expression, ';') {
+		statement = strings.TrimSpace(statement)
+		upper := strings.ToUpper(statement)
+		if strings.HasPrefix(upper, "REMOVE ") {
+			for _, name := range strings.Split(statement[len("REMOVE "):], ",") {
+				removed = append(removed, strings.TrimSpace(name))
+			}
+			continue
+		}
+		if strings.HasPrefix(upper, "SET ") {
+			statement = statement[len("SET "):]
+		}
+
+		eq := strings.Index(statement, "=")
+		if eq <= 0 {
+			continue
+		}
+		name := strings.TrimSpace(statement[:eq])
+		val, err := parseLiteral(strings.TrimSpace(statement[eq+1:]))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value for %s (%v)", name, err)
+		}
+		values[name] = val
+	}
+
+	return values, removed, nil
+}
+
+// decode a literal value, non literal expressions are returned as nil. Numbers must be finite,
+// the expression parser doesn't accept nan or inf
+func parseLiteral(expr string) (interface{}, error) {
+	if len(expr) >= 2 && expr[0] == '\'' && expr[len(expr)-1] == '\'' && !strings.Contains(expr[1:len(expr)-1], "'") {
+		return expr[1 : len(expr)-1], nil
+	}
+	if intValue, err := strconv.ParseInt(expr, 10, 64); err == nil {
+		return int(intValue), nil
+	}
+	if floatValue, err := strconv.ParseFloat(expr, 64); err == nil {
+		if math.IsNaN(floatValue) || math.IsInf(floatValue, 0) {
+			return nil, fmt.Errorf("%s is not a finite number", expr)
+		}
+		return floatValue, nil
+	}
+	return nil, nil
+}
+
+func splitOutsideQuotes(str string, sep rune) []string {
+	parts := []string{}
+	inQuote := false
+	start := 0
+	for i, r := range str {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+		case r == sep && !inQuote:
+			parts = append(parts, str[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, str[start:])
+}
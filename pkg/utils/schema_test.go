@@ -0,0 +1,152 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLiteral(t *testing.T) {
+	for _, test := range []struct {
+		expr  string
+		value interface{}
+		fail  bool
+	}{
+		{expr: "5", value: 5},
+		{expr: "-12", value: -12},
+		{expr: "2.5", value: 2.5},
+		{expr: "1e3", value: 1000.0},
+		{expr: "'good'", value: "good"},
+		{expr: "''", value: ""},
+		{expr: "'it's'", value: nil},
+		{expr: "z+1", value: nil},
+		{expr: "other", value: nil},
+		{expr: "nan", fail: true},
+		{expr: "NaN", fail: true},
+		{expr: "inf", fail: true},
+		{expr: "+Inf", fail: true},
+		{expr: "-Infinity", fail: true},
+	} {
+		value, err := parseLiteral(test.expr)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", test.expr, value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expr, err)
+		}
+		if !reflect.DeepEqual(value, test.value) {
+			t.Errorf("%s: got %v (%T), expected %v (%T)", test.expr, value, value, test.value, test.value)
+		}
+	}
+}
+
+func TestParseUpdateExpression(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		values     map[string]interface{}
+		removed    []string
+		fail       bool
+	}{
+		{expression: "x=5", values: map[string]interface{}{"x": 5}, removed: []string{}},
+		{expression: "x=5; SET y='a;b'; z=z+1; REMOVE w, v",
+			values: map[string]interface{}{"x": 5, "y": "a;b", "z": nil}, removed: []string{"w", "v"}},
+		{expression: "set a = 1.5", values: map[string]interface{}{"a": 1.5}, removed: []string{}},
+		{expression: "remove a", values: map[string]interface{}{}, removed: []string{"a"}},
+		{expression: "x=nan", fail: true},
+	} {
+		values, removed, err := parseUpdateExpression(test.expression)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.expression)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expression, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, test.values) || !reflect.DeepEqual(removed, test.removed) {
+			t.Errorf("%s: got %v %v, expected %v %v", test.expression, values, removed, test.values, test.removed)
+		}
+	}
+}
+
+func TestSchemaInferrer(t *testing.T) {
+	inferrer := NewSchemaInferrer("id")
+	for _, item := range []map[string]interface{}{
+		{"id": "a", "age": 30, "score": 1.5, "active": true, "data": []byte{1}, "note": "x"},
+		{"id": "b", "age": 2.5, "score": 2, "active": false, "data": []byte{2}},
+		{"id": "c", "age": 40, "score": "high", "active": true, "data": []byte{3}, "note": nil},
+	} {
+		if err := inferrer.Add(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := inferrer.Add(map[string]interface{}{"age": 1}); err == nil {
+		t.Error("expected an error for an item without the key")
+	}
+
+	expected := []OldSchemaField{
+		{Name: "active", Type: "boolean"},
+		{Name: "age", Type: "double"},
+		{Name: "data", Type: "blob"},
+		{Name: "id", Type: "string"},
+		{Name: "note", Type: "string", Nullable: true},
+		{Name: "score", Type: "string"},
+	}
+	schema := inferrer.Schema(16)
+	if !reflect.DeepEqual(schema.Fields, expected) || schema.Key != "id" || schema.HashingBucketNum != 16 {
+		t.Errorf("got %+v, expected fields %+v", schema, expected)
+	}
+}
+
+func TestCheckCompatible(t *testing.T) {
+	schema := &OldV3ioSchema{Key: "id", Fields: []OldSchemaField{
+		{Name: "id", Type: "string"}, {Name: "count", Type: "long"}, {Name: "score", Type: "double"},
+		{Name: "seen", Type: "time"},
+	}}
+
+	for _, test := range []struct {
+		name  string
+		field OldSchemaField
+		fail  bool
+	}{
+		{name: "same type", field: OldSchemaField{Name: "count", Type: "long"}},
+		{name: "new field", field: OldSchemaField{Name: "other", Type: "blob"}},
+		{name: "long to double", field: OldSchemaField{Name: "count", Type: "double"}},
+		{name: "double to long", field: OldSchemaField{Name: "score", Type: "long"}},
+		{name: "to string", field: OldSchemaField{Name: "score", Type: "string"}},
+		{name: "from time", field: OldSchemaField{Name: "seen", Type: "long"}, fail: true},
+		{name: "to time", field: OldSchemaField{Name: "count", Type: "time"}, fail: true},
+	} {
+		err := schema.CheckCompatible(&OldV3ioSchema{Key: "id", Fields: []OldSchemaField{test.field}})
+		if test.fail != (err != nil) {
+			t.Errorf("%s: got error %v, expected failure %v", test.name, err, test.fail)
+		}
+	}
+	if len(schema.Fields) != 4 || schema.Fields[0].Type != "string" || schema.Fields[1].Type != "long" {
+		t.Errorf("CheckCompatible changed the schema: %+v", schema.Fields)
+	}
+}
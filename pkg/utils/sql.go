@@ -0,0 +1,234 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SelectQuery is a parsed SELECT statement:
+// SELECT attrs FROM container.table [WHERE expr] [ORDER BY attr [ASC|DESC], ...] [LIMIT n]
+type SelectQuery struct {
+	Attributes []string
+	Container  string
+	Table      string
+	Filter     string
+	OrderBy    []OrderField
+	Limit      int
+}
+
+type OrderField struct {
+	Attribute  string
+	Descending bool
+}
+
+type sqlToken struct {
+	text  string
+	start int
+	end   int
+}
+
+// ParseSelect parses a SQL SELECT statement, the WHERE clause is translated to a v3io filter expression
+func ParseSelect(sql string) (*SelectQuery, error) {
+	tokens, err := tokenizeSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	// locate the clause keywords
+	clauses := map[string]int{}
+	order := []string{}
+	for i, token := range tokens {
+		keyword := strings.ToUpper(token.text)
+		switch keyword {
+		case "SELECT", "FROM", "WHERE", "LIMIT":
+		case "ORDER":
+			if i+1 >= len(tokens) || strings.ToUpper(tokens[i+1].text) != "BY" {
+				continue
+			}
+		default:
+			continue
+		}
+		if _, ok := clauses[keyword]; ok {
+			return nil, fmt.Errorf("duplicate %s clause in query", keyword)
+		}
+		clauses[keyword] = i
+		order = append(order, keyword)
+	}
+
+	if len(order) < 2 || order[0] != "SELECT" || order[1] != "FROM" || clauses["SELECT"] != 0 {
+		return nil, fmt.Errorf("query must start with SELECT <attrs> FROM <container>.<table>")
+	}
+
+	// the text of every clause runs until the start of the next one
+	clauseText := func(keyword string, skip int) string {
+		idx, ok := clauses[keyword]
+		if !ok {
+			return ""
+		}
+		start := tokens[idx+skip-1].end
+		end := len(sql)
+		for n, kw := range order {
+			if kw == keyword && n+1 < len(order) {
+				end = tokens[clauses[order[n+1]]].start
+			}
+		}
+		return strings.TrimSpace(sql[start:end])
+	}
+
+	query := SelectQuery{}
+	for _, attr := range strings.Split(clauseText("SELECT", 1), ",") {
+		if attr = strings.Trim(strings.TrimSpace(attr), "`"); attr != "" {
+			query.Attributes = append(query.Attributes, attr)
+		}
+	}
+	if len(query.Attributes) == 0 {
+		return nil, fmt.Errorf("missing attributes in SELECT clause")
+	}
+
+	from := strings.Replace(clauseText("FROM", 1), "`", "", -1)
+	dot := strings.Index(from, ".")
+	if dot <= 0 || dot == len(from)-1 || strings.ContainsAny(from, " \t") {
+		return nil, fmt.Errorf("FROM clause must be of the form <container>.<table>, got '%s'", from)
+	}
+	query.Container = from[:dot]
+	query.Table = from[dot+1:]
+
+	if _, ok := clauses["WHERE"]; ok {
+		query.Filter = translateFilter(clauseText("WHERE", 1))
+		if query.Filter == "" {
+			return nil, fmt.Errorf("empty WHERE clause")
+		}
+	}
+
+	if _, ok := clauses["ORDER"]; ok {
+		for _, field := range strings.Split(clauseText("ORDER", 2), ",") {
+			parts := strings.Fields(field)
+			if len(parts) == 0 || len(parts) > 2 {
+				return nil, fmt.Errorf("invalid ORDER BY field '%s'", field)
+			}
+			orderField := OrderField{Attribute: strings.Trim(parts[0], "`")}
+			if len(parts) == 2 {
+				switch strings.ToUpper(parts[1]) {
+				case "ASC":
+				case "DESC":
+					orderField.Descending = true
+				default:
+					return nil, fmt.Errorf("invalid ORDER BY direction '%s'", parts[1])
+				}
+			}
+			query.OrderBy = append(query.OrderBy, orderField)
+		}
+	}
+
+	if _, ok := clauses["LIMIT"]; ok {
+		query.Limit, err = strconv.Atoi(clauseText("LIMIT", 1))
+		if err != nil || query.Limit < 0 {
+			return nil, fmt.Errorf("invalid LIMIT '%s'", clauseText("LIMIT", 1))
+		}
+	}
+
+	return &query, nil
+}
+
+// split a statement to words and punctuation, keeping quoted strings as a single token
+func tokenizeSQL(sql string) ([]sqlToken, error) {
+	tokens := []sqlToken{}
+	runes := []rune(sql)
+	offsets := make([]int, len(runes)+1)
+	pos := 0
+	for i, r := range runes {
+		offsets[i] = pos
+		pos += len(string(r))
+	}
+	offsets[len(runes)] = pos
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '\'' || r == '"' || r == '`':
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated quote at position %d", offsets[start])
+			}
+			i++
+		case isWordRune(r):
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, sqlToken{text: string(runes[start:i]), start: offsets[start], end: offsets[i]})
+	}
+
+	return tokens, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.*/-`", r)
+}
+
+// translate SQL comparison operators to v3io filter syntax (= to ==, <> to !=), leaving string literals intact
+func translateFilter(where string) string {
+	var result strings.Builder
+	var quote rune
+	runes := []rune(where)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+			result.WriteRune(r)
+			continue
+		}
+
+		switch {
+		case r == '\'' || r == '"':
+			quote = r
+			result.WriteRune(r)
+		case r == '<' && i+1 < len(runes) && runes[i+1] == '>':
+			result.WriteString("!=")
+			i++
+		case r == '=' && i+1 < len(runes) && runes[i+1] == '=':
+			result.WriteString("==")
+			i++
+		case r == '=' && (i == 0 || !strings.ContainsRune("!<>", runes[i-1])):
+			result.WriteString("==")
+		default:
+			result.WriteRune(r)
+		}
+	}
+
+	return strings.TrimSpace(result.String())
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslateFilter(t *testing.T) {
+	for _, test := range []struct {
+		where  string
+		filter string
+	}{
+		{where: "age = 30", filter: "age == 30"},
+		{where: "age == 30", filter: "age == 30"},
+		{where: "age <> 30", filter: "age != 30"},
+		{where: "age >= 30 AND age <= 40 AND x != 1", filter: "age >= 30 AND age <= 40 AND x != 1"},
+		{where: "name = 'a=b' or name = \"c<>d\"", filter: "name == 'a=b' or name == \"c<>d\""},
+		{where: " a=1 ", filter: "a==1"},
+	} {
+		if filter := translateFilter(test.where); filter != test.filter {
+			t.Errorf("%s: got %s, expected %s", test.where, filter, test.filter)
+		}
+	}
+}
+
+func TestParseSelect(t *testing.T) {
+	for _, test := range []struct {
+		sql   string
+		query *SelectQuery
+		fail  bool
+	}{
+		{sql: "SELECT * FROM datalake.users",
+			query: &SelectQuery{Attributes: []string{"*"}, Container: "datalake", Table: "users"}},
+		{sql: "select name, `age` from datalake.dir/users where age > 30 and name = 'x' order by age desc, name limit 10",
+			query: &SelectQuery{Attributes: []string{"name", "age"}, Container: "datalake", Table: "dir/users",
+				Filter: "age > 30 and name == 'x'", Limit: 10,
+				OrderBy: []OrderField{{Attribute: "age", Descending: true}, {Attribute: "name"}}}},
+		{sql: "SELECT a FROM c.t WHERE note = 'order by limit'",
+			query: &SelectQuery{Attributes: []string{"a"}, Container: "c", Table: "t", Filter: "note == 'order by limit'"}},
+		{sql: "SELECT FROM c.t", fail: true},
+		{sql: "SELECT a FROM t", fail: true},
+		{sql: "FROM c.t SELECT a", fail: true},
+		{sql: "SELECT a FROM c.t WHERE", fail: true},
+		{sql: "SELECT a FROM c.t LIMIT x", fail: true},
+		{sql: "SELECT a FROM c.t LIMIT 1 LIMIT 2", fail: true},
+		{sql: "SELECT a FROM c.t ORDER BY a sideways", fail: true},
+		{sql: "SELECT a FROM c.t WHERE b = 'open", fail: true},
+	} {
+		query, err := ParseSelect(test.sql)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", test.sql, query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.sql, err)
+			continue
+		}
+		if !reflect.DeepEqual(query, test.query) {
+			t.Errorf("%s: got %+v, expected %+v", test.sql, query, test.query)
+		}
+	}
+}
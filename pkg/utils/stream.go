@@ -0,0 +1,542 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"github.com/v3io/v3io-go-http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StreamShard is a shard object of a stream
+type StreamShard struct {
+	ID             int
+	Path           string
+	Size           int
+	LastSequenceId int
+}
+
+// ListShards lists the shards of a stream sorted by shard id, and fails if the path is not a stream
+// (a directory holding only numbered shard objects)
+func ListShards(container *v3io.Container, streamPath string) ([]StreamShard, error) {
+	resp, err := container.Sync.ListBucket(&v3io.ListBucketInput{Path: streamPath})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Release()
+
+	output := resp.Output.(*v3io.ListBucketOutput)
+	if len(output.CommonPrefixes) > 0 || len(output.Contents) == 0 {
+		return nil, fmt.Errorf("%s is not a stream", streamPath)
+	}
+
+	shards := []StreamShard{}
+	for _, content := range output.Contents {
+		id, err := strconv.Atoi(path.Base(content.Key))
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a stream, found non shard object %s", streamPath, content.Key)
+		}
+		shards = append(shards, StreamShard{ID: id, Path: content.Key, Size: content.Size, LastSequenceId: content.LastSequenceId})
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i].ID < shards[j].ID })
+
+	return shards, nil
+}
+
+// ShardInfo describes the records held by a shard
+type ShardInfo struct {
+	ID               int        `json:"id"`
+	Path             string     `json:"path"`
+	Size             int        `json:"size"`
+	Records          int        `json:"records"`
+	EarliestSequence int        `json:"earliest_sequence,omitempty"`
+	LatestSequence   int        `json:"latest_sequence,omitempty"`
+	EarliestTime     *time.Time `json:"earliest_time,omitempty"`
+	LatestTime       *time.Time `json:"latest_time,omitempty"`
+}
+
+// DescribeShard reads the earliest record of a shard, and the latest record by the number of records behind it
+func DescribeShard(container *v3io.Container, shard StreamShard) (*ShardInfo, error) {
+	info := ShardInfo{ID: shard.ID, Path: shard.Path, Size: shard.Size}
+
+	first, behind, err := readShardRecord(container, shard.Path, &v3io.SeekShardInput{Type: v3io.SeekShardInputTypeEarliest})
+	if err != nil || first == nil {
+		return &info, err
+	}
+	info.Records = behind + 1
+	info.EarliestSequence = first.SequenceNumber
+	earliest := RecordTime(first)
+	info.EarliestTime, info.LatestTime = &earliest, &earliest
+	info.LatestSequence = first.SequenceNumber + behind
+
+	if behind > 0 {
+		last, _, err := readShardRecord(container, shard.Path,
+			&v3io.SeekShardInput{Type: v3io.SeekShardInputTypeSequence, StartingSequenceNumber: info.LatestSequence})
+		if err != nil {
+			return &info, err
+		}
+		if last != nil {
+			info.LatestSequence = last.SequenceNumber
+			latest := RecordTime(last)
+			info.LatestTime = &latest
+		}
+	}
+
+	return &info, nil
+}
+
+// SequenceBefore returns the sequence number preceding the first record at a seek position, which is the
+// checkpoint to resume reading from that position (zero resumes from the earliest record)
+func SequenceBefore(container *v3io.Container, shard StreamShard, input v3io.SeekShardInput) (int, error) {
+	if input.Type != v3io.SeekShardInputTypeLatest {
+		record, _, err := readShardRecord(container, shard.Path, &input)
+		if err != nil {
+			return 0, err
+		}
+		if record != nil {
+			return record.SequenceNumber - 1, nil
+		}
+	}
+
+	// at the end of the shard
+	info, err := DescribeShard(container, shard)
+	if err != nil {
+		return 0, err
+	}
+	return info.LatestSequence, nil
+}
+
+// read a single record from a seek location, returns the record (nil when there are no records) and the number
+// of records after it
+func readShardRecord(container *v3io.Container, shardPath string, input *v3io.SeekShardInput) (*v3io.GetRecordsResult, int, error) {
+	output, err := readShardOutput(container, shardPath, input)
+	if err != nil || len(output.Records) == 0 {
+		return nil, 0, err
+	}
+	return &output.Records[0], output.RecordsBehindLatest, nil
+}
+
+func readShardOutput(container *v3io.Container, shardPath string, input *v3io.SeekShardInput) (*v3io.GetRecordsOutput, error) {
+	input.Path = shardPath
+	resp, err := container.Sync.SeekShard(input)
+	if err != nil {
+		return nil, fmt.Errorf("Error in Seek operation on %s (%v)", shardPath, err)
+	}
+	location := resp.Output.(*v3io.SeekShardOutput).Location
+	resp.Release()
+
+	resp, err = container.Sync.GetRecords(&v3io.GetRecordsInput{Path: shardPath, Location: location, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("Error in GetRecords operation on %s (%v)", shardPath, err)
+	}
+	defer resp.Release()
+
+	return resp.Output.(*v3io.GetRecordsOutput), nil
+}
+
+// ShardLag returns the number of records after a sequence number (e.g. the position of a consumer) and how far
+// behind the latest record it is in time
+func ShardLag(container *v3io.Container, shard StreamShard, position int) (int, time.Duration, error) {
+	input := v3io.SeekShardInput{Type: v3io.SeekShardInputTypeSequence, StartingSequenceNumber: position + 1}
+	if position <= 0 {
+		input = v3io.SeekShardInput{Type: v3io.SeekShardInputTypeEarliest}
+	}
+
+	output, err := readShardOutput(container, shard.Path, &input)
+	if err != nil || len(output.Records) == 0 {
+		return 0, 0, err
+	}
+	return output.RecordsBehindLatest + 1, time.Duration(output.MSecBehindLatest) * time.Millisecond, nil
+}
+
+// RecordTime returns the arrival time of a stream record
+func RecordTime(record *v3io.GetRecordsResult) time.Time {
+	return time.Unix(int64(record.ArrivalTimeSec), int64(record.ArrivalTimeNSec))
+}
+
+// ShardRecord is a stream record and the id of the shard it was read from
+type ShardRecord struct {
+	Shard int
+	v3io.GetRecordsResult
+}
+
+type StreamReaderConfig struct {
+	// max records to get per GetRecords call
+	Limit int
+	// once all records were read wait this long and read again, zero stops at the end of the shards
+	Watch time.Duration
+	// return records as they are read rather than merged by arrival time
+	Unordered bool
+	// skip records that arrived before From, and stop reading a shard once its records arrived after To
+	// (zero values are unbounded)
+	From time.Time
+	To   time.Time
+}
+
+type shardBatch struct {
+	records  []v3io.GetRecordsResult
+	caughtUp bool
+	err      error
+}
+
+type shardState struct {
+	shard    StreamShard
+	batches  chan *shardBatch
+	queue    []v3io.GetRecordsResult
+	caughtUp bool
+	done     bool
+}
+
+// StreamReader reads the shards of a stream concurrently, each shard from its own seek position, and returns the
+// records merged by arrival time (or as they are read when Unordered is set)
+type StreamReader struct {
+	container *v3io.Container
+	config    StreamReaderConfig
+	shards    []*shardState
+	current   *ShardRecord
+	err       error
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func NewStreamReader(container *v3io.Container, shards []StreamShard, seek func(shard StreamShard) v3io.SeekShardInput,
+	config StreamReaderConfig) *StreamReader {
+
+	if config.Limit <= 0 {
+		config.Limit = 100
+	}
+
+	sr := StreamReader{container: container, config: config, stop: make(chan struct{})}
+	for _, shard := range shards {
+		state := shardState{shard: shard, batches: make(chan *shardBatch, 1)}
+		sr.shards = append(sr.shards, &state)
+		go sr.readShard(&state, seek(shard))
+	}
+
+	return &sr
+}
+
+func (sr *StreamReader) readShard(state *shardState, input v3io.SeekShardInput) {
+	defer close(state.batches)
+
+	send := func(batch *shardBatch) bool {
+		select {
+		case state.batches <- batch:
+			return true
+		case <-sr.stop:
+			return false
+		}
+	}
+
+	input.Path = state.shard.Path
+	resp, err := sr.container.Sync.SeekShard(&input)
+	if err != nil {
+		send(&shardBatch{err: fmt.Errorf("Error in Seek operation on %s (%v)", state.shard.Path, err)})
+		return
+	}
+	location := resp.Output.(*v3io.SeekShardOutput).Location
+	resp.Release()
+
+	for {
+		resp, err := sr.container.Sync.GetRecords(&v3io.GetRecordsInput{
+			Path: state.shard.Path, Location: location, Limit: sr.config.Limit})
+		if err != nil {
+			send(&shardBatch{err: fmt.Errorf("Error in GetRecords operation on %s (%v)", state.shard.Path, err)})
+			return
+		}
+		output := resp.Output.(*v3io.GetRecordsOutput)
+		resp.Release()
+
+		location = output.NextLocation
+		caughtUp := output.RecordsBehindLatest == 0
+		records, passedEnd := sr.filterTime(output.Records)
+		if !send(&shardBatch{records: records, caughtUp: caughtUp || passedEnd}) || passedEnd {
+			return
+		}
+
+		if caughtUp {
+			// records that arrive from now on are past the end time
+			if !sr.config.To.IsZero() && time.Now().After(sr.config.To) {
+				return
+			}
+			if sr.config.Watch == 0 {
+				return
+			}
+			select {
+			case <-time.After(sr.config.Watch):
+			case <-sr.stop:
+				return
+			}
+		}
+	}
+}
+
+// drop the records outside the time window, and report if the records passed the end time
+func (sr *StreamReader) filterTime(records []v3io.GetRecordsResult) ([]v3io.GetRecordsResult, bool) {
+	if sr.config.From.IsZero() && sr.config.To.IsZero() {
+		return records, false
+	}
+
+	filtered := []v3io.GetRecordsResult{}
+	for i := range records {
+		arrival := RecordTime(&records[i])
+		if !sr.config.To.IsZero() && arrival.After(sr.config.To) {
+			return filtered, true
+		}
+		if arrival.Before(sr.config.From) {
+			continue
+		}
+		filtered = append(filtered, records[i])
+	}
+
+	return filtered, false
+}
+
+// receive the next batch of a shard, returns false when not blocking and no batch is ready
+func (sr *StreamReader) receive(state *shardState, block bool) bool {
+	var batch *shardBatch
+	var ok bool
+	if block {
+		batch, ok = <-state.batches
+	} else {
+		select {
+		case batch, ok = <-state.batches:
+		default:
+			return false
+		}
+	}
+
+	sr.handleBatch(state, batch, ok)
+	return true
+}
+
+// wait for a batch from any of the active shards
+func (sr *StreamReader) receiveAny() {
+	cases := []reflect.SelectCase{}
+	states := []*shardState{}
+	for _, state := range sr.shards {
+		if !state.done {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(state.batches)})
+			states = append(states, state)
+		}
+	}
+
+	chosen, value, ok := reflect.Select(cases)
+	var batch *shardBatch
+	if ok {
+		batch = value.Interface().(*shardBatch)
+	}
+	sr.handleBatch(states[chosen], batch, ok)
+}
+
+func (sr *StreamReader) handleBatch(state *shardState, batch *shardBatch, ok bool) {
+	if !ok {
+		state.done = true
+		return
+	}
+	if batch.err != nil {
+		sr.err = batch.err
+		state.done = true
+		return
+	}
+	state.queue = append(state.queue, batch.records...)
+	state.caughtUp = batch.caughtUp
+}
+
+func (sr *StreamReader) active() bool {
+	for _, state := range sr.shards {
+		if !state.done || len(state.queue) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Next advances to the next record, returns false when all shards were read or on error
+func (sr *StreamReader) Next() bool {
+	for sr.err == nil {
+		if sr.config.Unordered {
+			for _, state := range sr.shards {
+				if len(state.queue) > 0 {
+					sr.pop(state)
+					return true
+				}
+			}
+		} else {
+			// a record can be returned once every shard has a pending record or has no records after it
+			for _, state := range sr.shards {
+				if !state.done && len(state.queue) == 0 {
+					sr.receive(state, !state.caughtUp)
+				}
+			}
+			if sr.err != nil {
+				break
+			}
+
+			var next *shardState
+			for _, state := range sr.shards {
+				if len(state.queue) > 0 && (next == nil || recordBefore(&state.queue[0], &next.queue[0])) {
+					next = state
+				}
+			}
+			if next != nil {
+				sr.pop(next)
+				return true
+			}
+		}
+
+		if !sr.active() {
+			return false
+		}
+		sr.receiveAny()
+	}
+
+	sr.Close()
+	return false
+}
+
+func (sr *StreamReader) pop(state *shardState) {
+	sr.current = &ShardRecord{Shard: state.shard.ID, GetRecordsResult: state.queue[0]}
+	state.queue = state.queue[1:]
+}
+
+func recordBefore(a, b *v3io.GetRecordsResult) bool {
+	if a.ArrivalTimeSec != b.ArrivalTimeSec {
+		return a.ArrivalTimeSec < b.ArrivalTimeSec
+	}
+	return a.ArrivalTimeNSec < b.ArrivalTimeNSec
+}
+
+// Record returns the current record
+func (sr *StreamReader) Record() *ShardRecord {
+	return sr.current
+}
+
+func (sr *StreamReader) Err() error {
+	return sr.err
+}
+
+// Close stops reading the shards, it may be called from another goroutine to end a blocked Next
+func (sr *StreamReader) Close() {
+	sr.stopOnce.Do(func() { close(sr.stop) })
+}
+
+// PutRecordsWithRetry puts a batch of records and retries the records that failed (with a non zero ErrorCode),
+// returns the result of every record in input order, records that still failed after the retries keep their
+// error code and message. When ordered is set, the later records with the partition key (or shard) of a failed
+// record are resent after it, so the records of a key keep their order (and those are written twice).
+// A request which fails as a whole is retried with the same records, so if it was in fact written the
+// records are written twice (at-least-once)
+func PutRecordsWithRetry(container *v3io.Container, streamPath string, records []*v3io.StreamRecord, retries int,
+	delay time.Duration, ordered bool) ([]v3io.PutRecordResult, error) {
+
+	results := make([]v3io.PutRecordResult, len(records))
+	pending := make([]int, len(records))
+	for i := range records {
+		pending[i] = i
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		batch := make([]*v3io.StreamRecord, len(pending))
+		for i, idx := range pending {
+			batch[i] = records[idx]
+		}
+
+		resp, err := container.Sync.PutRecords(&v3io.PutRecordsInput{Path: streamPath, Records: batch})
+		if err == nil {
+			output := resp.Output.(*v3io.PutRecordsOutput)
+			resp.Release()
+			failed := mapPutResults(pending, output.Records, results)
+			if ordered {
+				failed = withLaterRecords(pending, failed, records)
+			}
+			pending = failed
+		}
+
+		if attempt >= retries {
+			if err != nil {
+				return results, err
+			}
+			break
+		}
+		if len(pending) > 0 {
+			time.Sleep(delay << uint(attempt))
+		}
+	}
+
+	return results, nil
+}
+
+// store the results of the pending records (by their index in the input) and return the records which failed,
+// records without a result are failed
+func mapPutResults(pending []int, output []v3io.PutRecordResult, results []v3io.PutRecordResult) []int {
+	failed := []int{}
+	for i, idx := range pending {
+		if i >= len(output) {
+			results[idx] = v3io.PutRecordResult{ErrorCode: -1, ErrorMessage: "no result returned for the record"}
+		} else {
+			results[idx] = output[i]
+		}
+		if results[idx].ErrorCode != 0 {
+			failed = append(failed, idx)
+		}
+	}
+
+	return failed
+}
+
+// add to the failed records the pending records which follow a failed record with the same partition key
+// (or shard id), records without either have no order to keep
+func withLaterRecords(pending, failed []int, records []*v3io.StreamRecord) []int {
+	recordKey := func(record *v3io.StreamRecord) string {
+		if record.ShardID != nil {
+			return "shard:" + strconv.Itoa(*record.ShardID)
+		}
+		if record.PartitionKey != "" {
+			return "key:" + record.PartitionKey
+		}
+		return ""
+	}
+
+	isFailed := map[int]bool{}
+	for _, idx := range failed {
+		isFailed[idx] = true
+	}
+
+	retry := []int{}
+	failedKeys := map[string]bool{}
+	for _, idx := range pending {
+		key := recordKey(records[idx])
+		if isFailed[idx] || (key != "" && failedKeys[key]) {
+			retry = append(retry, idx)
+			if key != "" {
+				failedKeys[key] = true
+			}
+		}
+	}
+
+	return retry
+}
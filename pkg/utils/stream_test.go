@@ -0,0 +1,290 @@
+/*
+Copyright 2018 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/nuclio/logger"
+	"github.com/v3io/v3io-go-http"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Error(format interface{}, vars ...interface{})     {}
+func (nopLogger) Warn(format interface{}, vars ...interface{})      {}
+func (nopLogger) Info(format interface{}, vars ...interface{})      {}
+func (nopLogger) Debug(format interface{}, vars ...interface{})     {}
+func (nopLogger) ErrorWith(format interface{}, vars ...interface{}) {}
+func (nopLogger) WarnWith(format interface{}, vars ...interface{})  {}
+func (nopLogger) InfoWith(format interface{}, vars ...interface{})  {}
+func (nopLogger) DebugWith(format interface{}, vars ...interface{}) {}
+func (nopLogger) Flush()                                            {}
+func (l nopLogger) GetChild(name string) logger.Logger              { return l }
+
+// a fake web API serving a single shard holding the records with sequence numbers first..last,
+// each arriving a second after the previous one, and answering PutRecords with the handler
+type fakeStream struct {
+	first, last int
+	putRecords  func(records []map[string]interface{}) v3io.PutRecordsOutput
+}
+
+func (fs *fakeStream) arrival(seq int) int {
+	return 1000 + seq
+}
+
+func (fs *fakeStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	request := map[string]interface{}{}
+	json.Unmarshal(body, &request)
+
+	var response interface{}
+	switch r.Header.Get("X-v3io-function") {
+	case "SeekShard":
+		seq := fs.first
+		switch request["Type"] {
+		case "LATEST":
+			seq = fs.last + 1
+		case "SEQUENCE":
+			if start := int(request["StartingSequenceNumber"].(float64)); start > seq {
+				seq = start
+			}
+		case "TIME":
+			for seq <= fs.last && fs.arrival(seq) < int(request["TimestampSec"].(float64)) {
+				seq++
+			}
+		}
+		response = v3io.SeekShardOutput{Location: strconv.Itoa(seq)}
+	case "GetRecords":
+		seq, _ := strconv.Atoi(request["Location"].(string))
+		output := v3io.GetRecordsOutput{NextLocation: strconv.Itoa(seq), Records: []v3io.GetRecordsResult{}}
+		for ; seq <= fs.last && len(output.Records) < int(request["Limit"].(float64)); seq++ {
+			output.Records = append(output.Records, v3io.GetRecordsResult{
+				SequenceNumber: seq, ArrivalTimeSec: fs.arrival(seq), Data: []byte(strconv.Itoa(seq))})
+			output.NextLocation = strconv.Itoa(seq + 1)
+		}
+		if len(output.Records) > 0 {
+			output.RecordsBehindLatest = fs.last - output.Records[len(output.Records)-1].SequenceNumber
+		}
+		response = output
+	case "PutRecords":
+		records := []map[string]interface{}{}
+		for _, record := range request["Records"].([]interface{}) {
+			records = append(records, record.(map[string]interface{}))
+		}
+		response = fs.putRecords(records)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func newTestContainer(t *testing.T, handler http.Handler) (*v3io.Container, func()) {
+	server := httptest.NewServer(handler)
+	container, err := CreateContainer(nopLogger{}, strings.TrimPrefix(server.URL, "http://"), "test",
+		&v3io.SessionConfig{}, 1)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return container, server.Close
+}
+
+func TestMapPutResults(t *testing.T) {
+	ok := func(seq int) v3io.PutRecordResult { return v3io.PutRecordResult{SequenceNumber: seq, ShardID: 1} }
+	bad := v3io.PutRecordResult{ErrorCode: 503, ErrorMessage: "busy"}
+	missing := v3io.PutRecordResult{ErrorCode: -1, ErrorMessage: "no result returned for the record"}
+
+	for _, test := range []struct {
+		name    string
+		pending []int
+		output  []v3io.PutRecordResult
+		failed  []int
+		results []v3io.PutRecordResult
+	}{
+		{name: "all succeeded", pending: []int{0, 1, 2}, output: []v3io.PutRecordResult{ok(1), ok(2), ok(3)},
+			failed: []int{}, results: []v3io.PutRecordResult{ok(1), ok(2), ok(3)}},
+		{name: "middle failed", pending: []int{0, 1, 2}, output: []v3io.PutRecordResult{ok(1), bad, ok(2)},
+			failed: []int{1}, results: []v3io.PutRecordResult{ok(1), bad, ok(2)}},
+		{name: "retry maps to input index", pending: []int{1}, output: []v3io.PutRecordResult{ok(7)},
+			failed: []int{}, results: []v3io.PutRecordResult{{}, ok(7), {}}},
+		{name: "missing results fail", pending: []int{0, 2}, output: []v3io.PutRecordResult{ok(1)},
+			failed: []int{2}, results: []v3io.PutRecordResult{ok(1), {}, missing}},
+	} {
+		results := make([]v3io.PutRecordResult, 3)
+		failed := mapPutResults(test.pending, test.output, results)
+		if !reflect.DeepEqual(failed, test.failed) {
+			t.Errorf("%s: failed %v, expected %v", test.name, failed, test.failed)
+		}
+		if !reflect.DeepEqual(results, test.results) {
+			t.Errorf("%s: results %v, expected %v", test.name, results, test.results)
+		}
+	}
+}
+
+func TestWithLaterRecords(t *testing.T) {
+	shard := 3
+	records := []*v3io.StreamRecord{
+		{PartitionKey: "a"}, {PartitionKey: "b"}, {PartitionKey: "a"}, {}, {PartitionKey: "b"},
+		{ShardID: &shard}, {ShardID: &shard}, {},
+	}
+
+	for _, test := range []struct {
+		name    string
+		pending []int
+		failed  []int
+		retry   []int
+	}{
+		{name: "none failed", pending: []int{0, 1, 2, 3, 4, 5, 6, 7}, failed: []int{}, retry: []int{}},
+		{name: "later records of the key", pending: []int{0, 1, 2, 3, 4, 5, 6, 7}, failed: []int{0}, retry: []int{0, 2}},
+		{name: "earlier records of the key are kept", pending: []int{0, 1, 2, 3, 4, 5, 6, 7}, failed: []int{2}, retry: []int{2}},
+		{name: "shard id", pending: []int{0, 1, 2, 3, 4, 5, 6, 7}, failed: []int{1, 5}, retry: []int{1, 4, 5, 6}},
+		{name: "no key", pending: []int{0, 1, 2, 3, 4, 5, 6, 7}, failed: []int{3}, retry: []int{3}},
+		{name: "only pending records", pending: []int{0, 4}, failed: []int{0}, retry: []int{0}},
+	} {
+		retry := withLaterRecords(test.pending, test.failed, records)
+		if !reflect.DeepEqual(retry, test.retry) {
+			t.Errorf("%s: retry %v, expected %v", test.name, retry, test.retry)
+		}
+	}
+}
+
+func TestSequenceBefore(t *testing.T) {
+	container, closeServer := newTestContainer(t, &fakeStream{first: 5, last: 9})
+	defer closeServer()
+	shard := StreamShard{ID: 0, Path: "stream/0"}
+
+	for _, test := range []struct {
+		name     string
+		input    v3io.SeekShardInput
+		expected int
+	}{
+		{name: "earliest", input: v3io.SeekShardInput{Type: v3io.SeekShardInputTypeEarliest}, expected: 4},
+		{name: "latest", input: v3io.SeekShardInput{Type: v3io.SeekShardInputTypeLatest}, expected: 9},
+		{name: "sequence", input: v3io.SeekShardInput{Type: v3io.SeekShardInputTypeSequence, StartingSequenceNumber: 7},
+			expected: 6},
+		{name: "time", input: v3io.SeekShardInput{Type: v3io.SeekShardInputTypeTime, Timestamp: 1008}, expected: 7},
+		{name: "time after the last record", input: v3io.SeekShardInput{Type: v3io.SeekShardInputTypeTime, Timestamp: 2000},
+			expected: 9},
+	} {
+		seq, err := SequenceBefore(container, shard, test.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if seq != test.expected {
+			t.Errorf("%s: got %d, expected %d", test.name, seq, test.expected)
+		}
+	}
+}
+
+func TestPutRecordsWithRetry(t *testing.T) {
+	var lock sync.Mutex
+	attempts := map[string]int{}
+	sent := []string{}
+
+	// records with data "fail<n>" fail the first n attempts
+	stream := &fakeStream{putRecords: func(records []map[string]interface{}) v3io.PutRecordsOutput {
+		lock.Lock()
+		defer lock.Unlock()
+
+		output := v3io.PutRecordsOutput{}
+		for _, record := range records {
+			data, _ := base64Decode(record["Data"].(string))
+			attempts[data]++
+			sent = append(sent, data)
+			if strings.HasPrefix(data, "fail") {
+				if n, _ := strconv.Atoi(strings.TrimPrefix(data, "fail")); attempts[data] <= n {
+					output.FailedRecordCount++
+					output.Records = append(output.Records, v3io.PutRecordResult{ErrorCode: 503, ErrorMessage: "busy"})
+					continue
+				}
+			}
+			output.Records = append(output.Records, v3io.PutRecordResult{SequenceNumber: len(sent), ShardID: 1})
+		}
+		return output
+	}}
+	container, closeServer := newTestContainer(t, stream)
+	defer closeServer()
+
+	for _, test := range []struct {
+		name    string
+		data    []string
+		keys    []string
+		retries int
+		ordered bool
+		sent    []string
+		failed  []int
+	}{
+		{name: "no failures", data: []string{"a", "b"}, retries: 2, sent: []string{"a", "b"}, failed: []int{}},
+		{name: "retry the failed records only", data: []string{"a", "fail1", "b"}, retries: 2,
+			sent: []string{"a", "fail1", "b", "fail1"}, failed: []int{}},
+		{name: "retries exhausted", data: []string{"a", "fail5"}, retries: 1,
+			sent: []string{"a", "fail5", "fail5"}, failed: []int{1}},
+		{name: "ordered resends the later records of the key", data: []string{"fail1", "x", "y", "z"},
+			keys: []string{"k", "k", "j", ""}, retries: 2, ordered: true,
+			sent: []string{"fail1", "x", "y", "z", "fail1", "x"}, failed: []int{}},
+	} {
+		for name := range attempts {
+			delete(attempts, name)
+		}
+		sent = sent[:0]
+
+		records := make([]*v3io.StreamRecord, len(test.data))
+		for i, data := range test.data {
+			records[i] = &v3io.StreamRecord{Data: []byte(data)}
+			if test.keys != nil {
+				records[i].PartitionKey = test.keys[i]
+			}
+		}
+
+		results, err := PutRecordsWithRetry(container, "stream/", records, test.retries, time.Millisecond, test.ordered)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		failed := []int{}
+		for i, result := range results {
+			if result.ErrorCode != 0 {
+				failed = append(failed, i)
+			}
+		}
+		if !reflect.DeepEqual(sent, test.sent) || !reflect.DeepEqual(failed, test.failed) {
+			t.Errorf("%s: sent %v failed %v, expected sent %v failed %v", test.name, sent, failed, test.sent, test.failed)
+		}
+	}
+}
+
+func base64Decode(str string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(str)
+	return string(data), err
+}